@@ -32,10 +32,15 @@ import (
 	"crypto/rand"
 	"crypto/subtle"
 	"encoding/base64"
+	"encoding/hex"
 	"errors"
 	"fmt"
+	"io"
+	"math"
+	"net/url"
 	"strconv"
 	"strings"
+	"time"
 
 	"golang.org/x/crypto/argon2"
 )
@@ -79,19 +84,132 @@ var (
 
 	// ErrHashTooShort is returned when the provided hash is too short to be valid.
 	ErrHashTooShort = errors.New("argon2id: hash too short")
+
+	// ErrMismatchedHashAndPassword is returned when a password does not match its hash.
+	ErrMismatchedHashAndPassword = errors.New("argon2id: password does not match hash")
+
+	// ErrHashTooExpensive is returned when an untrusted hash's parameters
+	// exceed MaxTime or MaxMemory.
+	ErrHashTooExpensive = errors.New("argon2id: hash parameters exceed verification limits")
+
+	// ErrPasswordTooLong is returned when a password exceeds the configured
+	// maximum length.
+	ErrPasswordTooLong = errors.New("argon2id: password too long")
+
+	// ErrEmptyPassword is returned when an empty password is hashed and
+	// Params.RejectEmptyPassword is set.
+	ErrEmptyPassword = errors.New("argon2id: empty password")
+
+	// ErrTruncatedDigest is returned when a hash's digest decodes to a length
+	// outside MinKeyLen..MaxKeyLen, typically because the hash was cut off by
+	// a database column that is too short.
+	ErrTruncatedDigest = errors.New("argon2id: digest length out of range, hash may be truncated")
+
+	// ErrInvalidThreads is returned when a hash's "p=" parameter is outside
+	// 1..255. It wraps ErrInvalidHash, so errors.Is(err, ErrInvalidHash)
+	// still holds.
+	ErrInvalidThreads = fmt.Errorf("%w: parallelism out of range", ErrInvalidHash)
+
+	// ErrEmptySalt and ErrEmptyDigest are returned for a PHC hash whose salt
+	// or digest segment is empty, e.g. "$argon2id$v=19$m=65536,t=3,p=2$$hash",
+	// as left by a column that lost part of its data. Like ErrInvalidThreads
+	// they wrap ErrInvalidHash.
+	ErrEmptySalt   = fmt.Errorf("%w: empty salt segment", ErrInvalidHash)
+	ErrEmptyDigest = fmt.Errorf("%w: empty digest segment", ErrInvalidHash)
+
+	// ErrUnexpectedParams is returned by CompareHashAndPasswordExpect when a
+	// hash was not generated with the expected parameters.
+	ErrUnexpectedParams = errors.New("argon2id: hash parameters do not match the expected parameters")
+
+	// ErrWeakSalt is returned when RejectWeakSalt is set and a hash's salt
+	// is a single repeated byte.
+	ErrWeakSalt = errors.New("argon2id: salt is a repeated byte, hash may come from a broken generator")
+
+	// ErrNotFIPSApproved is returned instead of hashing or verifying in
+	// FIPS builds (the fips build tag or GOEXPERIMENT=boringcrypto), since
+	// Argon2 is not a FIPS-approved algorithm.
+	ErrNotFIPSApproved = errors.New("argon2id: argon2id is not a FIPS-approved algorithm")
 )
 
+// StrictDecode makes decoding fail closed: when set, a PHC hash whose stored
+// Time, Memory or Threads fall outside MinTime..MaxTime, MinMemory..MaxMemory
+// or MinThreads.. is rejected with ErrInvalidHash by every function that
+// decodes hashes, including CompareHashAndPasswordTrusted and ExtractParams.
+//
+// It defaults to false, in which case CompareHashAndPassword still refuses
+// hashes above the maximums (ErrHashTooExpensive) but other functions accept
+// them. Set it once during initialization, before any hashes are decoded.
+var StrictDecode bool
+
+// OnNonstandardSalt relaxes the salt length check on decode. By default a
+// hash whose salt is not SaltLen bytes is rejected with ErrInvalidHash. When
+// set, salts of at least MinSaltLen bytes are accepted and OnNonstandardSalt
+// is called with the length of each one that is not SaltLen, e.g. so a
+// migration can verify old 8-byte-salt hashes and log them for rehashing.
+//
+// It is called by every function that decodes hashes, possibly
+// concurrently, and must be fast and safe for concurrent use. Set it once
+// during initialization, before any hashes are decoded.
+var OnNonstandardSalt func(length int)
+
+// RejectWeakSalt makes every function that decodes hashes return
+// ErrWeakSalt for a hash whose salt is all zeros or one repeated byte, as
+// produced by a broken random generator, so audits catch such hashes. It
+// defaults to false; set it once during initialization, before any hashes
+// are decoded.
+var RejectWeakSalt bool
+
 // Params holds the Argon2ID algorithm parameters.
 //
 // Time controls the number of iterations over the memory.
 // Memory controls the size of the memory used (in KB).
 // Threads controls the number of threads used for parallelism.
 // KeyLen controls the length of the output key in bytes.
+//
+// RejectEmptyPassword makes GenerateFromPassword return ErrEmptyPassword for
+// a zero-length password instead of silently hashing it. It is not encoded
+// in the hash and defaults to false for backward compatibility.
+//
+// Encoding selects the serialization of generated hashes; the default is the
+// standard PHC string. Decoding detects the encoding automatically.
+//
+// Encoder, if set, replaces the unpadded standard base64 alphabet for the
+// salt and hash segments of PHC strings, e.g. for legacy columns that strip
+// '+' and '/'. It must not use padding, and its alphabet must not contain
+// '$', ',' or '=', which delimit the PHC string. The alphabet is recorded
+// in the hash as an "alphabet=" parameter, so every function that decodes
+// hashes reverses it without configuration, and the decoded Params carry an
+// equivalent Encoder.
+//
+// Extra holds any parameters beyond m, t and p found when decoding a PHC
+// string, e.g. from a newer encoder, as the raw comma-separated "key=value"
+// list in its original order. It is re-emitted after the known parameters
+// when the Params are used to generate a PHC hash, so such hashes round-trip
+// without data loss. It is a string rather than a map so that it does not
+// stop Params from being comparable. An explicit key length ("l="), which
+// some encoders add, must match the digest length when decoding and is
+// updated to KeyLen when encoding.
+//
+// PostHash, if set, wraps the digest of generated hashes (see
+// DigestTransform) and unwraps it when a Hasher with these Params verifies
+// a hash marked as wrapped. Unmarked hashes are still verified directly, so
+// existing hashes keep working while they are migrated. It requires
+// EncodingPHC, which is the only encoding that can carry the marker.
+//
+// Params can be compared with == and used as a map key (as ParamHistogram
+// does) only while PostHash is nil or holds a comparable type, such as a
+// pointer: comparing Params whose PostHash is, e.g., a func type panics.
+// Params decoded from a hash never have a PostHash.
 type Params struct {
-	Time    uint32 // Number of iterations
-	Memory  uint32 // Memory usage in KB
-	Threads uint8  // Number of threads (1-255)
-	KeyLen  uint32 // Output key length in bytes
+	Time                uint32           // Number of iterations
+	Memory              uint32           // Memory usage in KB
+	Threads             uint8            // Number of threads (1-255)
+	KeyLen              uint32           // Output key length in bytes
+	RejectEmptyPassword bool             // Reject zero-length passwords
+	Encoding            EncodingMode     // Hash serialization (PHC by default)
+	Encoder             *base64.Encoding // Salt and hash alphabet (RawStdEncoding by default)
+	Extra               string           // Unknown PHC parameters, e.g. "x=42"
+	PostHash            DigestTransform  // Digest wrapping, e.g. with an HSM key
 }
 
 // DefaultParams returns a new Params struct with secure default values.
@@ -107,6 +225,71 @@ func DefaultParams() *Params {
 	}
 }
 
+// Clone returns a copy of p that can be modified without affecting p, e.g.
+// to derive per-request params from a shared application-wide value. The
+// Encoder, which is immutable, is shared. Clone of a nil *Params is nil.
+func (p *Params) Clone() *Params {
+	if p == nil {
+		return nil
+	}
+	c := *p
+	return &c
+}
+
+// Equal reports whether p and other produce the same digests: whether their
+// Time, Memory, Threads and KeyLen match. How a hash is encoded (Encoder,
+// Encoding, Extra) and generation options are not compared. Two nil
+// *Params are equal.
+func (p *Params) Equal(other *Params) bool {
+	if p == nil || other == nil {
+		return p == other
+	}
+	return p.Time == other.Time && p.Memory == other.Memory &&
+		p.Threads == other.Threads && p.KeyLen == other.KeyLen
+}
+
+// AssociatedData returns the decoded value of the PHC "data=" parameter in
+// p.Extra, or nil if there is none. Encoders that support Argon2 associated
+// data record it there, and it is preserved through Extra when the hash is
+// re-encoded. golang.org/x/crypto/argon2 cannot bind associated data, so a
+// hash whose digest was computed with it will not verify with this package;
+// AssociatedData lets such hashes be recognized. The marker written for
+// PostHash is not associated data and yields nil.
+func (p *Params) AssociatedData() ([]byte, error) {
+	for _, param := range strings.Split(p.Extra, ",") {
+		value, ok := strings.CutPrefix(param, "data=")
+		if !ok || param == postHashMarker {
+			continue
+		}
+		data, err := base64.RawStdEncoding.Strict().DecodeString(value)
+		if err != nil {
+			return nil, fmt.Errorf("argon2id: invalid data parameter: %w", err)
+		}
+		return data, nil
+	}
+	return nil, nil
+}
+
+// IsDefaultParams reports whether hashedPassword was generated with exactly
+// DefaultParams() (see Params.Equal), e.g. to count users still on the
+// library defaults rather than custom or stronger settings.
+func IsDefaultParams(hashedPassword []byte) (bool, error) {
+	params, err := ExtractParams(hashedPassword)
+	if err != nil {
+		return false, err
+	}
+	return params.Equal(DefaultParams()), nil
+}
+
+// ConstantTimeHashEqual reports whether the encoded hashes a and b are
+// byte-for-byte identical, in time that depends only on their lengths. Use
+// it instead of == or bytes.Equal when comparing whole stored hashes, e.g.
+// to check that an upgrade left a hash unchanged. To check a password, use
+// CompareHashAndPassword.
+func ConstantTimeHashEqual(a, b []byte) bool {
+	return subtle.ConstantTimeCompare(a, b) == 1
+}
+
 // GenerateFromPassword creates an Argon2ID hash from the given password.
 //
 // The password parameter should be the plaintext password as a byte slice.
@@ -124,73 +307,235 @@ func DefaultParams() *Params {
 // - Threads must be >= 1
 // - KeyLen must be >= 4 bytes and <= 128 bytes
 //
-// Returns an error if parameters are outside these bounds.
+// Returns an error if parameters are outside these bounds, or
+// ErrEmptyPassword if params.RejectEmptyPassword is set and password is empty.
 func GenerateFromPassword(password []byte, params *Params) ([]byte, error) {
+	hash, _, err := GenerateFromPasswordWithUsedParams(password, params)
+	return hash, err
+}
+
+// GenerateFromPasswordWithUsedParams is like GenerateFromPassword but also
+// returns the effective parameters the hash was generated with.
+//
+// This is useful when params is nil: the caller learns which defaults were
+// applied without having to parse the hash with ExtractParams. The returned
+// Params is a copy and may be modified freely.
+func GenerateFromPasswordWithUsedParams(password []byte, params *Params) (hash []byte, used *Params, err error) {
+	return generateFromPassword(password, nil, params)
+}
+
+// GenerateFromPasswordWipe is like GenerateFromPassword but zeroes password
+// before returning, whether or not hashing succeeded, so the plaintext does
+// not linger in the caller's buffer.
+//
+// The caller must not use the slice afterwards. This only clears this one
+// buffer: copies made elsewhere, e.g. by string conversions or an HTTP
+// framework, are unaffected.
+func GenerateFromPasswordWipe(password []byte, params *Params) ([]byte, error) {
+	defer clear(password)
+	return GenerateFromPassword(password, params)
+}
+
+// randReader is the source of random salts. Tests replace it to exercise
+// the error path, which crypto/rand itself never takes.
+var randReader io.Reader = rand.Reader
+
+// generateFromPassword implements GenerateFromPasswordWithUsedParams. A
+// non-empty userSalt is appended to the random salt for the key derivation
+// but not stored in the hash.
+func generateFromPassword(password, userSalt []byte, params *Params) (hash []byte, used *Params, err error) {
+	salt := make([]byte, SaltLen)
+	if _, err := io.ReadFull(randReader, salt); err != nil {
+		return nil, nil, err
+	}
+	return hashWithSalt(password, salt, userSalt, params)
+}
+
+// hashWithSalt hashes password with the given stored salt.
+func hashWithSalt(password, salt, userSalt []byte, params *Params) (hash []byte, used *Params, err error) {
 	if params == nil {
 		params = DefaultParams()
 	}
 
-	// Validate parameters
-	if params.Time < MinTime {
-		return nil, fmt.Errorf("argon2id: Time (%d) is too low, must be >= %d", params.Time, MinTime)
-	}
-	if params.Time > MaxTime {
-		return nil, fmt.Errorf("argon2id: Time (%d) is too high, must be <= %d", params.Time, MaxTime)
-	}
-	if params.Memory < MinMemory {
-		return nil, fmt.Errorf("argon2id: Memory (%d KB) is too low, must be >= %d KB", params.Memory, MinMemory)
-	}
-	if params.Memory > MaxMemory {
-		return nil, fmt.Errorf("argon2id: Memory (%d KB) is too high, must be <= %d KB", params.Memory, MaxMemory)
-	}
-	if params.Threads < MinThreads {
-		return nil, fmt.Errorf("argon2id: Threads (%d) is too low, must be >= %d", params.Threads, MinThreads)
-	}
-	if params.KeyLen < MinKeyLen {
-		return nil, fmt.Errorf("argon2id: KeyLen (%d) is too low, must be >= %d", params.KeyLen, MinKeyLen)
-	}
-	if params.KeyLen > MaxKeyLen {
-		return nil, fmt.Errorf("argon2id: KeyLen (%d) is too high, must be <= %d", params.KeyLen, MaxKeyLen)
+	if fipsRestricted {
+		return nil, nil, ErrNotFIPSApproved
 	}
 
-	salt := make([]byte, SaltLen)
-	if _, err := rand.Read(salt); err != nil {
-		return nil, err
+	if params.RejectEmptyPassword && len(password) == 0 {
+		return nil, nil, ErrEmptyPassword
 	}
 
-	hash := argon2.IDKey(password, salt, params.Time, params.Memory, params.Threads, params.KeyLen)
+	if err := validateParams(params); err != nil {
+		return nil, nil, err
+	}
 
-	// Format: $argon2id$v=19$m=memory,t=time,p=threads$salt$hash
-	encodedSalt := base64.RawStdEncoding.EncodeToString(salt)
-	encodedHash := base64.RawStdEncoding.EncodeToString(hash)
+	digest := idKey(password, mixSalt(salt, userSalt), params)
+	if params.PostHash != nil {
+		if digest, err = params.PostHash.Wrap(digest); err != nil {
+			return nil, nil, err
+		}
+	}
+	hash = encodeHash(params, salt, digest)
 
-	format := "$argon2id$v=19$m=%d,t=%d,p=%d$%s$%s"
-	return []byte(fmt.Sprintf(format, params.Memory, params.Time, params.Threads, encodedSalt, encodedHash)), nil
+	return hash, params.Clone(), nil
 }
 
 // CompareHashAndPassword compares a plaintext password with an Argon2ID hash.
 //
-// Returns nil if the password matches the hash, otherwise returns an error.
+// Returns nil if the password matches the hash, ErrMismatchedHashAndPassword
+// if it does not, or a decoding error if the hash is malformed.
 // The comparison is performed in constant time to prevent timing attacks.
 //
 // The hashedPassword parameter should be a hash previously generated by
 // GenerateFromPassword. The password parameter should be the plaintext
 // password to verify.
+//
+// Hashes in a compact encoding (EncodingRaw, EncodingBinary) do not record
+// their cost, so they are verified with the default parameters. Use a
+// Hasher to verify them with custom parameters.
+//
+// The hash is treated as untrusted: if its Time or Memory exceed MaxTime or
+// MaxMemory, ErrHashTooExpensive is returned without computing anything, so
+// an attacker-supplied hash cannot force an arbitrarily expensive
+// computation. Use CompareHashAndPasswordTrusted for hashes from your own
+// storage that predate the current limits.
 func CompareHashAndPassword(hashedPassword, password []byte) error {
-	params, salt, hash, err := decodeHash(string(hashedPassword))
+	return compareHashAndPassword(hashedPassword, password, verifyOptions{enforceLimits: true})
+}
+
+// CompareHashAndPasswordTrusted is like CompareHashAndPassword but skips the
+// verify-time MaxTime and MaxMemory limits.
+//
+// Only use it for trusted hashes, i.e. hashes this application generated
+// and read back from its own database. Legacy hashes created with stronger
+// parameters than the current limits (e.g. 2 GB of memory) remain verifiable
+// this way, so tightening the limits never locks out existing users.
+//
+// Never pass an untrusted hash, such as one supplied in a request or by an
+// import from a third party: its parameters control how much CPU and memory
+// the verification consumes.
+func CompareHashAndPasswordTrusted(hashedPassword, password []byte) error {
+	return compareHashAndPassword(hashedPassword, password, verifyOptions{})
+}
+
+// CompareHashAndPasswordTimed is like CompareHashAndPassword but also
+// reports how long the Argon2 computation took.
+//
+// The duration covers only the key derivation: decoding and time spent
+// queued behind SetMaxConcurrency are excluded, so it tracks the cost of
+// the stored params on the current hardware and is suitable for alerting
+// on latency drift. It is zero if the hash was rejected before hashing
+// (e.g. malformed or ErrHashTooExpensive).
+func CompareHashAndPasswordTimed(hashedPassword, password []byte) (time.Duration, error) {
+	_, elapsed, err := verifyHash(hashedPassword, password, verifyOptions{enforceLimits: true})
+	return elapsed, err
+}
+
+// compareHashAndPassword decodes the hash, optionally enforces the
+// verify-time limits, and compares in constant time.
+func compareHashAndPassword(hashedPassword, password []byte, opts verifyOptions) error {
+	_, _, err := verifyHash(hashedPassword, password, opts)
+	return err
+}
+
+// CompareHashAndPasswordURLEncoded is like CompareHashAndPassword for a
+// hash that travelled percent-encoded in a URL, e.g. "%24argon2id%24v=19...".
+// It decodes percent escapes before verifying and returns ErrInvalidHash if
+// they are malformed. A '+' is kept as is rather than read as a space, since
+// it is part of the base64 alphabet and hashes are often put in URLs with
+// only '$' escaped.
+func CompareHashAndPasswordURLEncoded(encodedHash, password []byte) error {
+	hash, err := url.PathUnescape(string(encodedHash))
+	if err != nil {
+		return ErrInvalidHash
+	}
+	return CompareHashAndPassword([]byte(hash), password)
+}
+
+// CompareHashAndPasswordExpect is like CompareHashAndPassword but first
+// checks that the hash was generated with exactly the expected params (see
+// Params.Equal), returning ErrUnexpectedParams without hashing if not.
+//
+// It is for deployments that enforce a single parameter set: a hash that
+// does not match the current policy is refused rather than verified, so
+// the application can force a password reset instead of silently accepting
+// a weaker hash. If expected is nil, DefaultParams() will be used.
+func CompareHashAndPasswordExpect(hashedPassword, password []byte, expected *Params) error {
+	if expected == nil {
+		expected = DefaultParams()
+	}
+
+	params, salt, hash, err := decodeHash(string(hashedPassword), nil)
 	if err != nil {
 		return err
 	}
+	if !params.Equal(expected) {
+		return ErrUnexpectedParams
+	}
+
+	_, err = verifyDecoded(params, salt, hash, password, verifyOptions{})
+	return err
+}
+
+// verifyOptions configures verifyHash.
+type verifyOptions struct {
+	// fallback supplies the cost parameters for compact encodings, the
+	// Encoder and the PostHash transform (see decodeHash and unwrapDigest)
+	fallback *Params
+	// kdf replaces argon2.IDKey if not nil
+	kdf KDF
+	// userSalt, if not empty, is appended to the stored salt, matching
+	// generateFromPassword
+	userSalt []byte
+	// enforceLimits refuses hashes above MaxTime or MaxMemory
+	enforceLimits bool
+}
+
+// verifyHash implements the compare functions. It returns the decoded
+// params (also on a mismatch) and the duration of the key derivation.
+func verifyHash(hashedPassword, password []byte, opts verifyOptions) (*Params, time.Duration, error) {
+	params, salt, hash, err := decodeHashCached(string(hashedPassword), opts.fallback)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	if opts.enforceLimits && (params.Time > MaxTime || params.Memory > MaxMemory) {
+		return nil, 0, ErrHashTooExpensive
+	}
+
+	elapsed, err := verifyDecoded(params, salt, hash, password, opts)
+	if err != nil && err != ErrMismatchedHashAndPassword {
+		return nil, 0, err
+	}
+	return params, elapsed, err
+}
+
+// verifyDecoded computes the hash of password for a decoded hash and
+// compares it with the stored digest.
+func verifyDecoded(params *Params, salt, hash, password []byte, opts verifyOptions) (time.Duration, error) {
+	if fipsRestricted {
+		return 0, ErrNotFIPSApproved
+	}
+
+	hash, err := unwrapDigest(params, hash, opts.fallback)
+	if err != nil {
+		return 0, err
+	}
+
+	kdf := opts.kdf
+	if kdf == nil {
+		kdf = argon2.IDKey
+	}
 
 	// Generate hash with same parameters
-	computedHash := argon2.IDKey(password, salt, params.Time, params.Memory, params.Threads, params.KeyLen)
+	computedHash, elapsed := deriveTimed(kdf, password, mixSalt(salt, opts.userSalt), params)
 
 	// Use constant time comparison
 	if subtle.ConstantTimeCompare(hash, computedHash) == 1 {
-		return nil
+		return elapsed, nil
 	}
 
-	return errors.New("argon2id: password does not match hash")
+	return elapsed, ErrMismatchedHashAndPassword
 }
 
 // ExtractParams extracts the Argon2ID parameters from a hash string.
@@ -204,13 +549,67 @@ func CompareHashAndPassword(hashedPassword, password []byte) error {
 // The hashedPassword parameter should be a hash generated by this package
 // or another compatible Argon2ID implementation.
 func ExtractParams(hashedPassword []byte) (*Params, error) {
-	params, _, _, err := decodeHash(string(hashedPassword))
+	params, _, _, err := decodeHash(string(hashedPassword), nil)
 	if err != nil {
 		return nil, err
 	}
 	return params, nil
 }
 
+// ExtractSalt returns the decoded salt of a hash.
+//
+// It is meant for security tooling, e.g. auditing RNG quality or detecting
+// salts reused across hashes, without recomputing anything.
+func ExtractSalt(hashedPassword []byte) ([]byte, error) {
+	_, salt, _, err := decodeHash(string(hashedPassword), nil)
+	if err != nil {
+		return nil, err
+	}
+	return salt, nil
+}
+
+// ParseHash decodes a hash into its parameters, salt and digest without
+// verifying anything. It accepts every format CompareHashAndPassword does;
+// compact EncodingRaw and EncodingBinary hashes report DefaultParams() cost
+// parameters, since they do not record their own. The digest of a hash
+// wrapped by a DigestTransform is returned as stored.
+func ParseHash(hashedPassword []byte) (params *Params, salt, digest []byte, err error) {
+	return decodeHash(string(hashedPassword), nil)
+}
+
+// GenerateFromPasswordWithSalt is like GenerateFromPassword but uses the
+// given salt instead of a random one. The salt must be SaltLen bytes.
+//
+// It exists for reproducible test vectors and for tooling that re-encodes
+// hashes. Never use it with a fixed or reused salt for real passwords:
+// the random salt is what makes identical passwords hash differently.
+func GenerateFromPasswordWithSalt(password, salt []byte, params *Params) ([]byte, error) {
+	if len(salt) != SaltLen {
+		return nil, fmt.Errorf("argon2id: salt length (%d) must be %d", len(salt), SaltLen)
+	}
+	hash, _, err := hashWithSalt(password, salt, nil, params)
+	return hash, err
+}
+
+// IsArgon2idHash reports whether hash is a well-formed Argon2ID PHC string
+// that this package can verify.
+//
+// It checks the structure only (variant, version, parameters, salt and
+// digest) and does no hashing, so it is cheap enough for validating input
+// or fixtures. Compact EncodingRaw and EncodingBinary hashes carry no
+// marker and are not recognized.
+func IsArgon2idHash(hash []byte) bool {
+	_, _, _, err := decodePHC(string(hash))
+	return err == nil
+}
+
+// HashPasswordPair is a hash together with the password it was generated
+// from, e.g. a known-good fixture.
+type HashPasswordPair struct {
+	Hash     []byte
+	Password []byte
+}
+
 // NeedsRehash checks if a hash was generated with weaker parameters than the provided ones.
 //
 // It compares the time and memory parameters of the hash with the given newParams.
@@ -233,46 +632,418 @@ func NeedsRehash(hashedPassword []byte, newParams *Params) (bool, error) {
 	if err != nil {
 		return false, err
 	}
-	return oldParams.Time < newParams.Time || oldParams.Memory < newParams.Memory, nil
+	return weakerThan(oldParams, newParams), nil
+}
+
+// weakerThan reports whether stored has a lower Time or Memory than desired,
+// the criterion of NeedsRehash.
+func weakerThan(stored, desired *Params) bool {
+	return stored.Time < desired.Time || stored.Memory < desired.Memory
+}
+
+// OnRehash, if set, is called whenever RehashIfNeeded,
+// Hasher.CompareAndUpgrade or MigrateStream generates a stronger hash to
+// replace a stored one, with the stored params and the params of the new
+// hash, e.g. to keep an audit trail of cost increases. It is not called
+// when no upgrade happens.
+//
+// The params passed are copies. It may be called concurrently and must be
+// safe for concurrent use. Set it once during initialization.
+var OnRehash func(oldParams, newParams *Params)
+
+// upgradeHash generates the hash replacing one stored with old params and
+// reports the upgrade to OnRehash.
+func upgradeHash(password []byte, old, desired *Params) ([]byte, error) {
+	hash, used, err := GenerateFromPasswordWithUsedParams(password, desired)
+	if err != nil {
+		return nil, err
+	}
+	if OnRehash != nil {
+		OnRehash(old.Clone(), used)
+	}
+	return hash, nil
+}
+
+// RehashIfNeeded verifies password against hashedPassword and returns the
+// hash the caller should store.
+//
+// If the hash already meets desired (see NeedsRehash), hashedPassword is
+// returned unchanged; otherwise a new hash is generated with desired. Either
+// way the caller can store the result unconditionally after a successful
+// login. If the password does not match, ErrMismatchedHashAndPassword is
+// returned and no hash is generated. If desired is nil, DefaultParams() will
+// be used.
+//
+// Example usage:
+//
+//	hash, err := argon2id.RehashIfNeeded(user.Hash, password, appParams)
+//	if err != nil {
+//	    return err // wrong password or malformed hash
+//	}
+//	user.Hash = hash
+func RehashIfNeeded(hashedPassword, password []byte, desired *Params) ([]byte, error) {
+	if desired == nil {
+		desired = DefaultParams()
+	}
+
+	stored, _, err := verifyHash(hashedPassword, password, verifyOptions{enforceLimits: true})
+	if err != nil {
+		return nil, err
+	}
+	if !weakerThan(stored, desired) {
+		return hashedPassword, nil
+	}
+
+	return upgradeHash(password, stored, desired)
+}
+
+// Resalt verifies password against hashedPassword and, if it matches,
+// returns a new hash with the same parameters and a fresh random salt.
+//
+// It is for policies that require periodic re-salting at equal strength;
+// use RehashIfNeeded to upgrade parameters instead. The hash is parsed
+// once and the parameters it was decoded with are reused as-is, so a hash
+// whose parameters are outside the Min/Max limits returns an error rather
+// than being silently upgraded. If the password does not match,
+// ErrMismatchedHashAndPassword is returned and no hash is generated.
+func Resalt(hashedPassword, password []byte) ([]byte, error) {
+	params, _, err := verifyHash(hashedPassword, password, verifyOptions{enforceLimits: true})
+	if err != nil {
+		return nil, err
+	}
+	return GenerateFromPassword(password, params)
 }
 
-// decodeHash parses an Argon2ID hash string and returns the parameters, salt, and hash
-func decodeHash(hash string) (*Params, []byte, []byte, error) {
+// validateParams checks params against the Min/Max parameter limits
+func validateParams(params *Params) error {
+	if params.Time < MinTime {
+		return fmt.Errorf("argon2id: Time (%d) is too low, must be >= %d", params.Time, MinTime)
+	}
+	if params.Time > MaxTime {
+		return fmt.Errorf("argon2id: Time (%d) is too high, must be <= %d", params.Time, MaxTime)
+	}
+	if params.Memory < MinMemory {
+		return fmt.Errorf("argon2id: Memory (%d KB) is too low, must be >= %d KB", params.Memory, MinMemory)
+	}
+	if params.Memory > MaxMemory {
+		return fmt.Errorf("argon2id: Memory (%d KB) is too high, must be <= %d KB", params.Memory, MaxMemory)
+	}
+	if params.Threads < MinThreads {
+		return fmt.Errorf("argon2id: Threads (%d) is too low, must be >= %d", params.Threads, MinThreads)
+	}
+	if params.KeyLen < MinKeyLen {
+		return fmt.Errorf("argon2id: KeyLen (%d) is too low, must be >= %d", params.KeyLen, MinKeyLen)
+	}
+	if params.KeyLen > MaxKeyLen {
+		return fmt.Errorf("argon2id: KeyLen (%d) is too high, must be <= %d", params.KeyLen, MaxKeyLen)
+	}
+	if err := validateEncoding(params); err != nil {
+		return err
+	}
+	return validateExtra(params.Extra)
+}
+
+// validateEncoding checks that the Encoding is known and can carry the
+// PostHash marker if one is needed.
+func validateEncoding(params *Params) error {
+	if params.Encoding > EncodingBinary {
+		return fmt.Errorf("argon2id: unknown Encoding (%d)", params.Encoding)
+	}
+	if params.PostHash != nil && params.Encoding != EncodingPHC {
+		return fmt.Errorf("argon2id: PostHash requires EncodingPHC, not %s", params.Encoding)
+	}
+	if params.Encoder != nil {
+		if params.Encoder.EncodedLen(1) != params.Encoder.WithPadding(base64.NoPadding).EncodedLen(1) {
+			return errors.New("argon2id: Encoder must not use padding")
+		}
+		if !validAlphabet(encoderAlphabet(params.Encoder)) {
+			return errors.New("argon2id: Encoder alphabet must be printable ASCII without '$', ',' or '='")
+		}
+	}
+	return nil
+}
+
+// validateExtra checks that extra is a comma-separated list of "key=value"
+// pairs that does not redefine m, t or p.
+func validateExtra(extra string) error {
+	if extra == "" {
+		return nil
+	}
+	for _, param := range strings.Split(extra, ",") {
+		key, value, found := strings.Cut(param, "=")
+		if !found || key == "" || value == "" || strings.ContainsRune(key, '$') || strings.ContainsAny(value, "=$") {
+			return fmt.Errorf("argon2id: Extra parameter %q is not of the form key=value", param)
+		}
+		if key == "m" || key == "t" || key == "p" {
+			return fmt.Errorf("argon2id: Extra parameter %q redefines a known parameter", param)
+		}
+	}
+	return nil
+}
+
+// decodeHash parses a serialized hash and returns the parameters, salt, and hash.
+// The compact encodings do not record the cost parameters, which are taken
+// from fallback instead (DefaultParams() if nil). A trailing line ending
+// and a scheme label prefix are stripped first (see trimLineEnding and
+// stripSchemeLabel).
+func decodeHash(hash string, fallback *Params) (*Params, []byte, []byte, error) {
+	hash = stripSchemeLabel(trimLineEnding(hash))
+	mode := detectEncoding(hash)
+	if mode == EncodingPHC {
+		return decodePHC(hash)
+	}
+
+	var salt, hashBytes []byte
+	var err error
+	if mode == EncodingRaw {
+		salt, hashBytes, err = decodeRaw(hash)
+	} else {
+		salt, hashBytes, err = decodeBinary(hash)
+	}
+	if err != nil {
+		return nil, nil, nil, err
+	}
+
+	if fallback == nil {
+		fallback = DefaultParams()
+	}
+	params := &Params{
+		Time:     fallback.Time,
+		Memory:   fallback.Memory,
+		Threads:  fallback.Threads,
+		Encoding: mode,
+	}
+
+	return checkDecoded(params, salt, hashBytes)
+}
+
+// trimLineEnding removes one trailing "\r\n", "\n" or "\r", as left by
+// reading a hash from a file written on any platform. Without it only the
+// standard base64 path would tolerate them, because encoding/base64 skips
+// newlines; hex, constant-time and EncodingRaw decoding would not. Binary
+// hashes end in raw digest bytes and are returned unchanged.
+func trimLineEnding(hash string) string {
+	if len(hash) > 0 && hash[0] == binaryPrefix {
+		return hash
+	}
+	return strings.TrimSuffix(strings.TrimSuffix(hash, "\n"), "\r")
+}
+
+// stripSchemeLabel removes an "argon2id:" prefix (in any case), which
+// databases holding several hash algorithms in one column sometimes store
+// as a discriminator, e.g. "argon2id:$argon2id$v=19$...". The PHC decoder
+// ignores text before the first '$' anyway; stripping the label here makes
+// labeled compact EncodingRaw hashes decode too.
+func stripSchemeLabel(hash string) string {
+	const label = "argon2id:"
+	if len(hash) > len(label) && strings.EqualFold(hash[:len(label)], label) {
+		return hash[len(label):]
+	}
+	return hash
+}
+
+// decodePHC parses an Argon2ID PHC string, layering the Argon2id
+// validation on the generic PHC splitter shared with ParsePHC. The salt and
+// hash segments are decoded with the alphabet recorded in the parameters,
+// if any.
+func decodePHC(hash string) (*Params, []byte, []byte, error) {
 	if len(hash) < MinHashLength {
 		return nil, nil, nil, ErrHashTooShort
 	}
 
-	parts := strings.Split(hash, "$")
-	if len(parts) != 6 {
+	phc, ok := splitArgon2idPHC(hash)
+	if !ok || phc.params == "" || phc.segments != 2 {
 		return nil, nil, nil, ErrInvalidHash
 	}
 
-	if err := validateVariantAndVersion(parts[1], parts[2]); err != nil {
+	if phc.salt == "" {
+		return nil, nil, nil, ErrEmptySalt
+	}
+	if phc.hash == "" {
+		return nil, nil, nil, ErrEmptyDigest
+	}
+
+	if err := validateVariantAndVersion(phc.id, phc.version); err != nil {
 		return nil, nil, nil, err
 	}
 
-	params, err := parseParams(parts[3])
+	params, err := parseParams(phc.params)
 	if err != nil {
 		return nil, nil, nil, err
 	}
+	if StrictDecode && !withinPolicy(params) {
+		return nil, nil, nil, ErrInvalidHash
+	}
 
-	salt, err := base64.RawStdEncoding.DecodeString(parts[4])
+	encoder, err := recordedEncoder(params.Extra)
 	if err != nil {
-		return nil, nil, nil, ErrInvalidHash
+		return nil, nil, nil, err
+	}
+	salt, hashBytes, err := decodeSaltAndHash(phc.salt, phc.hash, encoder)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	params.Encoder = encoder
+
+	// A wrapped digest is checked against "l=" once unwrapped
+	if !hasPostHashMarker(params.Extra) {
+		if err := checkKeyLenParam(params.Extra, len(hashBytes)); err != nil {
+			return nil, nil, nil, err
+		}
+	}
+	return checkDecoded(params, salt, hashBytes)
+}
+
+// checkKeyLenParam validates the explicit key length that some encoders
+// add as an "l=" parameter (kept in Extra) against the digest length.
+func checkKeyLenParam(extra string, keyLen int) error {
+	for _, param := range strings.Split(extra, ",") {
+		value, ok := strings.CutPrefix(param, "l=")
+		if !ok {
+			continue
+		}
+		if n, err := parseNumber(value, 32); err != nil || n != uint64(keyLen) { // #nosec G115 - len() is non-negative
+			return ErrInvalidHash
+		}
+	}
+	return nil
+}
+
+// withinPolicy reports whether decoded cost parameters fall within the
+// limits GenerateFromPassword enforces.
+func withinPolicy(params *Params) bool {
+	return params.Time >= MinTime && params.Time <= MaxTime &&
+		params.Memory >= MinMemory && params.Memory <= MaxMemory &&
+		params.Threads >= MinThreads
+}
+
+// splitArgon2idPHC splits an Argon2id PHC string with splitPHCString,
+// tolerating what non-conforming encoders and serializers add: text before
+// the first '$' (such as a scheme label), and a single extra '$' at either
+// end, as added by serializers that wrap values in delimiters.
+func splitArgon2idPHC(hash string) (phcString, bool) {
+	if i := strings.IndexByte(hash, '$'); i > 0 {
+		hash = hash[i:]
+	}
+	if strings.HasPrefix(hash, "$$") {
+		hash = hash[1:]
+	}
+	phc, ok := splitPHCString(hash)
+	if !ok {
+		// A trailing '$' after the hash segment; "$salt$" instead splits
+		// into an empty hash segment, reported as ErrEmptyDigest
+		if rest, trimmed := strings.CutSuffix(hash, "$"); trimmed {
+			phc, ok = splitPHCString(rest)
+		}
+	}
+	if !ok {
+		phc, ok = splitUnprefixedVersion(hash)
 	}
+	return phc, ok
+}
+
+// splitUnprefixedVersion splits a hash whose version segment lacks "v="
+// (e.g. "$argon2id$19$m=..."), which splitPHCString takes for a salt, so
+// that it is reported as ErrIncompatibleVersion rather than ErrInvalidHash.
+func splitUnprefixedVersion(hash string) (phcString, bool) {
+	id, rest, _ := strings.Cut(strings.TrimPrefix(hash, "$"), "$")
+	version, rest, found := strings.Cut(rest, "$")
+	if !found || strings.IndexByte(version, '=') >= 0 {
+		return phcString{}, false
+	}
+	phc, ok := splitPHCString("$" + id + "$" + rest)
+	if !ok || phc.version != "" || phc.params == "" {
+		return phcString{}, false
+	}
+	phc.version = version
+	return phc, true
+}
 
-	hashBytes, err := base64.RawStdEncoding.DecodeString(parts[5])
+// decodeSaltAndHash decodes the salt and hash segments as base64, padded or
+// unpadded independently per segment, and falls back to hex in either case
+// for tools that emit hex segments. Hex digits are also valid base64, so hex
+// is tried when the base64 salt does not decode to SaltLen bytes.
+//
+// A hash recorded with a custom alphabet is decoded with encoder only. With
+// ConstantTimeDecode set, only constant-time standard base64 is tried.
+func decodeSaltAndHash(encodedSalt, encodedHash string, encoder *base64.Encoding) ([]byte, []byte, error) {
+	if ConstantTimeDecode {
+		salt, saltOK := decodeBase64ConstantTime(encodedSalt)
+		hashBytes, hashOK := decodeBase64ConstantTime(encodedHash)
+		if saltOK && hashOK && encoder == nil {
+			return salt, hashBytes, nil
+		}
+		return nil, nil, ErrInvalidHash
+	}
+
+	if encoder != nil {
+		// The salt length is checked by checkDecoded
+		salt, hashBytes, _ := decodeBase64Segments(encoder, encodedSalt, encodedHash)
+		if salt == nil || hashBytes == nil {
+			return nil, nil, ErrInvalidHash
+		}
+		return salt, hashBytes, nil
+	}
+
+	salt, hashBytes, ok := decodeBase64Segments(base64.RawStdEncoding, encodedSalt, encodedHash)
+	if ok {
+		return salt, hashBytes, nil
+	}
+
+	if hexSalt, err := hex.DecodeString(encodedSalt); err == nil {
+		if hexHash, err := hex.DecodeString(encodedHash); err == nil {
+			return hexSalt, hexHash, nil
+		}
+	}
+
+	if salt == nil || hashBytes == nil {
+		return nil, nil, ErrInvalidHash
+	}
+	return salt, hashBytes, nil
+}
+
+// decodeBase64Segments decodes both segments with enc. ok reports whether
+// both decoded and the salt has the expected length; on a length mismatch
+// the decoded segments are still returned, and on a decode error they are nil.
+func decodeBase64Segments(enc *base64.Encoding, encodedSalt, encodedHash string) (salt, hashBytes []byte, ok bool) {
+	salt, err := decodeBase64Segment(enc, encodedSalt)
 	if err != nil {
-		return nil, nil, nil, ErrInvalidHash
+		return nil, nil, false
 	}
+	hashBytes, err = decodeBase64Segment(enc, encodedHash)
+	if err != nil {
+		return nil, nil, false
+	}
+	return salt, hashBytes, len(salt) == SaltLen
+}
+
+// decodeBase64Segment decodes a single segment with enc. Segments are
+// decoded independently so that a padded segment written by a
+// non-conforming encoder next to an unpadded one still decodes: a segment
+// ending in '=' is retried with padding for the standard alphabet.
+func decodeBase64Segment(enc *base64.Encoding, segment string) ([]byte, error) {
+	decoded, err := enc.DecodeString(segment)
+	if err != nil && enc == base64.RawStdEncoding && strings.HasSuffix(segment, "=") {
+		return base64.StdEncoding.DecodeString(segment)
+	}
+	return decoded, err
+}
 
+// checkDecoded validates the decoded salt and hash lengths and sets KeyLen
+func checkDecoded(params *Params, salt, hashBytes []byte) (*Params, []byte, []byte, error) {
 	// Validate lengths
-	if len(salt) != SaltLen {
+	if !acceptSaltLen(len(salt)) {
 		return nil, nil, nil, ErrInvalidHash
 	}
+	if RejectWeakSalt && isRepeatedByte(salt) {
+		return nil, nil, nil, ErrWeakSalt
+	}
 	if len(hashBytes) == 0 {
 		return nil, nil, nil, ErrInvalidHash
 	}
+	if len(hashBytes) < MinKeyLen || len(hashBytes) > MaxKeyLen {
+		return nil, nil, nil, ErrTruncatedDigest
+	}
 
 	// Set key length based on hash length
 	params.KeyLen = uint32(len(hashBytes)) // #nosec G115 - len() returns non-negative int, safe conversion
@@ -280,65 +1051,194 @@ func decodeHash(hash string) (*Params, []byte, []byte, error) {
 	return params, salt, hashBytes, nil
 }
 
+// acceptSaltLen reports whether a decoded salt length is acceptable,
+// reporting nonstandard lengths to OnNonstandardSalt.
+func acceptSaltLen(length int) bool {
+	if length == SaltLen {
+		return true
+	}
+	if OnNonstandardSalt == nil || length < MinSaltLen {
+		return false
+	}
+	OnNonstandardSalt(length)
+	return true
+}
+
+// isRepeatedByte reports whether b consists of a single repeated byte.
+func isRepeatedByte(b []byte) bool {
+	for _, c := range b {
+		if c != b[0] {
+			return false
+		}
+	}
+	return true
+}
+
 // validateVariantAndVersion checks the algorithm variant and version
+// segment. Some tools capitalize the variant ("Argon2id", "ARGON2ID"), so it
+// is compared case-insensitively. Older libsodium crypto_pwhash_str output
+// omits the version segment, so a missing one is read as version 19.
 func validateVariantAndVersion(variant, version string) error {
-	if variant != "argon2id" {
+	if !strings.EqualFold(variant, "argon2id") {
 		return ErrIncompatibleVariant
 	}
+	if version == "" {
+		return nil
+	}
 
-	if version != "v=19" {
+	if v, ok := parseVersion(version); !ok || v != argon2.Version {
 		return ErrIncompatibleVersion
 	}
 
 	return nil
 }
 
-// parseParams parses the parameters section of the hash
-func parseParams(paramString string) (*Params, error) {
-	params := &Params{}
-	paramParts := strings.Split(paramString, ",")
-	if len(paramParts) != 3 {
-		return nil, ErrInvalidHash
+// parseVersion parses the "v=" segment numerically. Some encoders emit the
+// version in hex ("v=0x13") rather than decimal ("v=19"), so both are
+// accepted; other bases are not.
+func parseVersion(version string) (uint64, bool) {
+	value, ok := strings.CutPrefix(version, "v=")
+	if !ok {
+		return 0, false
+	}
+
+	v, err := parseNumber(value, 32)
+	if err != nil {
+		return 0, false
 	}
+	return v, true
+}
 
-	for _, param := range paramParts {
-		if err := parseParam(params, param); err != nil {
+// parseNumber parses an unsigned decimal number, or a hex one with a "0x"
+// prefix as some encoders emit for the version and numeric parameters.
+// Other bases and prefixes are rejected.
+func parseNumber(value string, bitSize int) (uint64, error) {
+	if hexValue, isHex := strings.CutPrefix(value, "0x"); isHex {
+		return strconv.ParseUint(hexValue, 16, bitSize)
+	}
+	return strconv.ParseUint(value, 10, bitSize)
+}
+
+// parseParams parses the parameters section of the hash: m, t and p, each
+// exactly once, in any order and mixed with unknown key=value pairs, which
+// are kept in Extra in their original order. It scans in place so that
+// hashes without unknown parameters decode without extra allocations.
+func parseParams(paramString string) (*Params, error) {
+	params := &Params{}
+	var seen uint8
+	var extra strings.Builder
+	for rest, more := paramString, true; more; {
+		var param string
+		param, rest, more = strings.Cut(rest, ",")
+		key, known, err := parseParam(params, param)
+		if err != nil {
 			return nil, err
 		}
+		if !known {
+			if extra.Len() > 0 {
+				extra.WriteByte(',')
+			}
+			extra.WriteString(param)
+			continue
+		}
+		bit := knownParamBit(key)
+		if seen&bit != 0 {
+			return nil, ErrInvalidHash
+		}
+		seen |= bit
+	}
+	if seen != knownParamBit("m")|knownParamBit("t")|knownParamBit("p") {
+		return nil, ErrInvalidHash
 	}
+	params.Extra = extra.String()
 
 	return params, nil
 }
 
-// parseParam parses a single parameter key=value pair
-func parseParam(params *Params, param string) error {
-	keyValue := strings.Split(param, "=")
-	if len(keyValue) != 2 {
-		return ErrInvalidHash
+// knownParamBit returns the bit parseParams tracks a known parameter with.
+func knownParamBit(key string) uint8 {
+	switch key {
+	case "m":
+		return 1
+	case "t":
+		return 2
+	default: // "p"
+		return 4
+	}
+}
+
+// parseMemory parses an "m=" value in KB. Some non-standard encoders add a
+// unit suffix, so "K" and "KiB" (KB) and "MiB" (1024 KB) are accepted too,
+// as is a "0x" hex value (see parseNumber).
+func parseMemory(value string) (uint32, error) {
+	multiplier := uint64(1)
+	switch {
+	case strings.HasSuffix(value, "MiB"):
+		value, multiplier = strings.TrimSuffix(value, "MiB"), 1024
+	case strings.HasSuffix(value, "KiB"):
+		value = strings.TrimSuffix(value, "KiB")
+	case strings.HasSuffix(value, "K"):
+		value = strings.TrimSuffix(value, "K")
+	}
+
+	memory, err := parseNumber(value, 32)
+	if err != nil {
+		return 0, err
+	}
+	memory *= multiplier
+	if memory > math.MaxUint32 {
+		return 0, strconv.ErrRange
+	}
+	return uint32(memory), nil
+}
+
+// parseThreads parses a "p=" value. A number outside MinThreads..255
+// returns ErrInvalidThreads rather than ErrInvalidHash so tooling can report
+// it; p=0 in particular is refused on decode just as Threads < MinThreads is
+// on generation.
+func parseThreads(value string) (uint8, error) {
+	threads, err := parseNumber(value, 8)
+	if errors.Is(err, strconv.ErrRange) || (err == nil && threads < MinThreads) {
+		return 0, ErrInvalidThreads
+	}
+	if err != nil {
+		return 0, ErrInvalidHash
 	}
+	return uint8(threads), nil
+}
 
-	switch keyValue[0] {
+// parseParam parses a single parameter key=value pair into params. known
+// reports whether key is m, t or p; other keys are returned unparsed for
+// Extra.
+func parseParam(params *Params, param string) (key string, known bool, err error) {
+	key, value, found := strings.Cut(param, "=")
+	if !found || key == "" || value == "" || strings.IndexByte(value, '=') >= 0 {
+		return "", false, ErrInvalidHash
+	}
+
+	switch key {
 	case "m":
-		value, err := strconv.ParseUint(keyValue[1], 10, 32)
+		memory, err := parseMemory(value)
 		if err != nil {
-			return ErrInvalidHash
+			return "", false, ErrInvalidHash
 		}
-		params.Memory = uint32(value)
+		params.Memory = memory
 	case "t":
-		value, err := strconv.ParseUint(keyValue[1], 10, 32)
-		if err != nil {
-			return ErrInvalidHash
+		// t=0 is refused as argon2.IDKey panics on it
+		value, err := parseNumber(value, 32)
+		if err != nil || value < MinTime {
+			return "", false, ErrInvalidHash
 		}
 		params.Time = uint32(value)
 	case "p":
-		value, err := strconv.ParseUint(keyValue[1], 10, 8)
+		threads, err := parseThreads(value)
 		if err != nil {
-			return ErrInvalidHash
+			return "", false, err
 		}
-		params.Threads = uint8(value)
+		params.Threads = threads
 	default:
-		return ErrInvalidHash
+		return key, false, nil
 	}
 
-	return nil
+	return key, true, nil
 }
@@ -1,9 +1,22 @@
+//go:build !fips && !goexperiment.boringcrypto
+
 package argon2id
 
 import (
+	"bytes"
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"net/url"
 	"regexp"
+	"slices"
 	"strings"
 	"testing"
+	"testing/iotest"
+	"time"
+
+	"golang.org/x/crypto/argon2"
 )
 
 func TestGenerateFromPassword(t *testing.T) {
@@ -26,11 +39,53 @@ func TestGenerateFromPassword(t *testing.T) {
 		t.Fatal(err)
 	}
 
-	if strings.Compare(string(hash1), string(hash2)) == 0 {
+	if ConstantTimeHashEqual(hash1, hash2) {
 		t.Error("hashes must be unique")
 	}
 }
 
+func TestGenerateFromPasswordRandError(t *testing.T) {
+	errRand := errors.New("entropy source failed")
+	randReader = iotest.ErrReader(errRand)
+	t.Cleanup(func() { randReader = rand.Reader })
+
+	params := &Params{Time: 1, Memory: 1024, Threads: 1, KeyLen: 32}
+	if _, err := GenerateFromPassword([]byte("password"), params); !errors.Is(err, errRand) {
+		t.Errorf("GenerateFromPassword: expected %v, got %v", errRand, err)
+	}
+	if _, err := MeasureHashTime(params); !errors.Is(err, errRand) {
+		t.Errorf("MeasureHashTime: expected %v, got %v", errRand, err)
+	}
+
+	// A short read is an error too, never a partially random salt
+	randReader = bytes.NewReader(make([]byte, SaltLen/2))
+	if _, err := GenerateFromPassword([]byte("password"), params); err == nil {
+		t.Error("expected error for a short read")
+	}
+}
+
+func TestConstantTimeHashEqual(t *testing.T) {
+	hash, err := GenerateFromPassword([]byte("pa$$word"), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !ConstantTimeHashEqual(hash, append([]byte(nil), hash...)) {
+		t.Error("expected a copy of a hash to be equal")
+	}
+	if ConstantTimeHashEqual(hash, hash[:len(hash)-1]) {
+		t.Error("expected a truncated hash to differ")
+	}
+	other := append([]byte(nil), hash...)
+	other[len(other)-1] ^= 1
+	if ConstantTimeHashEqual(hash, other) {
+		t.Error("expected hashes differing in one byte to differ")
+	}
+	if !ConstantTimeHashEqual(nil, []byte{}) {
+		t.Error("expected nil and empty to be equal")
+	}
+}
+
 func TestCompareHashAndPassword(t *testing.T) {
 	hash, err := GenerateFromPassword([]byte("pa$$word"), nil)
 	if err != nil {
@@ -137,7 +192,56 @@ func TestDecodeHashErrors(t *testing.T) {
 			hash:    "$argon2id$v=19$m=65536",
 			wantErr: ErrHashTooShort,
 		},
-		// ...rest of test cases...
+		{
+			name:    "too many parts",
+			hash:    "$argon2id$v=19$m=65536,t=3,p=2$mFe3kxhovyEByvwnUtr0ow$nU9AqnoPfzMOQhCHa9BDrQ$extra",
+			wantErr: ErrInvalidHash,
+		},
+		{
+			name:    "too few params",
+			hash:    "$argon2id$v=19$m=65536,t=3$mFe3kxhovyEByvwnUtr0ow$nU9AqnoPfzMOQhCHa9BDrQ",
+			wantErr: ErrInvalidHash,
+		},
+		{
+			name:    "duplicate param",
+			hash:    "$argon2id$v=19$m=65536,t=3,p=2,p=2$mFe3kxhovyEByvwnUtr0ow$nU9AqnoPfzMOQhCHa9BDrQ",
+			wantErr: ErrInvalidHash,
+		},
+		{
+			name:    "param without value",
+			hash:    "$argon2id$v=19$m=65536,t3,p=2$mFe3kxhovyEByvwnUtr0ow$nU9AqnoPfzMOQhCHa9BDrQ",
+			wantErr: ErrInvalidHash,
+		},
+		{
+			name:    "zero time",
+			hash:    "$argon2id$v=19$m=65536,t=0,p=2$mFe3kxhovyEByvwnUtr0ow$nU9AqnoPfzMOQhCHa9BDrQ",
+			wantErr: ErrInvalidHash,
+		},
+		{
+			name:    "param with two values",
+			hash:    "$argon2id$v=19$m=65536,t=3=4,p=2$mFe3kxhovyEByvwnUtr0ow$nU9AqnoPfzMOQhCHa9BDrQ",
+			wantErr: ErrInvalidHash,
+		},
+		{
+			name:    "unknown param in place of a known one",
+			hash:    "$argon2id$v=19$m=65536,x=3,p=2$mFe3kxhovyEByvwnUtr0ow$nU9AqnoPfzMOQhCHa9BDrQ",
+			wantErr: ErrInvalidHash,
+		},
+		{
+			name:    "non-numeric param",
+			hash:    "$argon2id$v=19$m=lots,t=3,p=2$mFe3kxhovyEByvwnUtr0ow$nU9AqnoPfzMOQhCHa9BDrQ",
+			wantErr: ErrInvalidHash,
+		},
+		{
+			name:    "invalid salt",
+			hash:    "$argon2id$v=19$m=65536,t=3,p=2$mFe3kxhovyEBy!wnUtr0ow$nU9AqnoPfzMOQhCHa9BDrQ",
+			wantErr: ErrInvalidHash,
+		},
+		{
+			name:    "short salt",
+			hash:    "$argon2id$v=19$m=65536,t=3,p=2$mFe3kxhovyEB$nU9AqnoPfzMOQhCHa9BDrQ",
+			wantErr: ErrInvalidHash,
+		},
 	}
 
 	for _, tt := range tests {
@@ -298,6 +402,10 @@ func TestParamBoundaryValues(t *testing.T) {
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
+			if !tt.expectError {
+				skipExpensive(t, tt.params)
+			}
+
 			hash, err := GenerateFromPassword([]byte("test"), tt.params)
 			if tt.expectError {
 				if err == nil {
@@ -348,6 +456,33 @@ func BenchmarkGenerateFromPassword(b *testing.B) {
 	}
 }
 
+// BenchmarkGenerate shows how hashing latency scales with Params.Memory at
+// the default Time and Threads, to help pick parameters for a deployment:
+//
+//	go test -run '^$' -bench BenchmarkGenerate/ -benchtime 10x
+//
+// Run it on production-like hardware. ns/op is the latency of one login; a
+// common target is 250ms-1s. B/op is roughly the memory each concurrent
+// hash holds, so multiply it by the expected concurrent logins (see
+// SetMaxConcurrency) and keep the total within what the host can spare.
+// Latency should grow about linearly with memory; a steeper jump usually
+// means the working set no longer fits in cache or RAM is contended.
+func BenchmarkGenerate(b *testing.B) {
+	password := []byte("benchmarkPassword123")
+	for _, memoryMB := range []uint32{16, 64, 256} {
+		params := DefaultParams()
+		params.Memory = memoryMB * 1024
+		b.Run(fmt.Sprintf("m=%dMB", memoryMB), func(b *testing.B) {
+			b.ReportAllocs()
+			for i := 0; i < b.N; i++ {
+				if _, err := GenerateFromPassword(password, params); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
+
 func BenchmarkCompareHashAndPassword(b *testing.B) {
 	password := []byte("benchmarkPassword123")
 	hash, err := GenerateFromPassword(password, nil)
@@ -364,6 +499,36 @@ func BenchmarkCompareHashAndPassword(b *testing.B) {
 	}
 }
 
+// BenchmarkCompareAttackerParams shows the cost asymmetry of verifying a
+// hash whose parameters an attacker controls: "default" is what a
+// legitimate login costs, "max" is the most a hash within MaxTime and
+// MaxMemory can cost, and "over-limit" must be rejected by the verify-time
+// limits before any hashing. The max case takes minutes per operation;
+// run it with e.g. -bench CompareAttackerParams -benchtime 1x.
+func BenchmarkCompareAttackerParams(b *testing.B) {
+	const saltAndDigest = "$mFe3kxhovyEByvwnUtr0ow$nU9AqnoPfzMOQhCHa9BDrQ+4bSfj69jgtvGu/2McCxU"
+	cases := []struct {
+		name    string
+		hash    string
+		wantErr error
+	}{
+		{"default", "$argon2id$v=19$m=65536,t=3,p=2" + saltAndDigest, ErrMismatchedHashAndPassword},
+		{"max", "$argon2id$v=19$m=1048576,t=100,p=2" + saltAndDigest, ErrMismatchedHashAndPassword},
+		{"over-limit", "$argon2id$v=19$m=4194304,t=1000,p=2" + saltAndDigest, ErrHashTooExpensive},
+	}
+
+	password := []byte("benchmarkPassword123")
+	for _, tc := range cases {
+		b.Run(tc.name, func(b *testing.B) {
+			for i := 0; i < b.N; i++ {
+				if err := CompareHashAndPassword([]byte(tc.hash), password); err != tc.wantErr {
+					b.Fatalf("expected %v, got %v", tc.wantErr, err)
+				}
+			}
+		})
+	}
+}
+
 // Fuzz tests
 func FuzzGenerateFromPassword(f *testing.F) {
 	f.Add([]byte("password"), uint32(3), uint32(65536), uint8(2), uint32(32))
@@ -396,6 +561,39 @@ func FuzzCompareHashAndPassword(f *testing.F) {
 	})
 }
 
+// FuzzDecodeHash checks that decoding never panics and that every hash it
+// accepts re-encodes to a hash that decodes to the same params, salt and
+// digest.
+func FuzzDecodeHash(f *testing.F) {
+	for _, encoding := range []EncodingMode{EncodingPHC, EncodingRaw, EncodingBinary} {
+		hash, _ := GenerateFromPassword([]byte("password"), &Params{Time: 1, Memory: 1024, Threads: 1, KeyLen: 32, Encoding: encoding})
+		f.Add(hash)
+	}
+	f.Add([]byte("$argon2id$v=19$m=64K,t=2,p=1,keyid=abc$PD90ckFJR9sRjzSrUtbKlQ$FKYaq32UmwYMabKEcCSOsy0Z9unTzMTPV8mKfD5q/bM"))
+	f.Add([]byte("$argon2id$m=1024,t=2,p=1$PD90ckFJR9sRjzSrUtbKlQ$FKYaq32UmwYMabKEcCSOsy0Z9unTzMTPV8mKfD5q/bM"))
+	f.Fuzz(func(t *testing.T, hash []byte) {
+		params, salt, digest, err := ParseHash(hash)
+		if err != nil {
+			return
+		}
+
+		// Wrapped digests keep their marker only while PostHash is set
+		if hasPostHashMarker(params.Extra) {
+			params.PostHash = xorTransform{}
+		}
+		reencoded := encodeHash(params, salt, digest)
+
+		params2, salt2, digest2, err := ParseHash(reencoded)
+		if err != nil {
+			t.Fatalf("re-encoded hash %q of %q does not decode: %v", reencoded, hash, err)
+		}
+		params2.PostHash = params.PostHash
+		if *params2 != *params || !bytes.Equal(salt2, salt) || !bytes.Equal(digest2, digest) {
+			t.Errorf("round trip of %q through %q changed %+v to %+v", hash, reencoded, params, params2)
+		}
+	})
+}
+
 func TestNeedsRehash(t *testing.T) {
 	// Generate hash with default params
 	hash, err := GenerateFromPassword([]byte("test"), nil)
@@ -442,3 +640,1205 @@ func TestNeedsRehash(t *testing.T) {
 		t.Error("expected no rehash needed for weaker params")
 	}
 }
+
+func TestRejectEmptyPassword(t *testing.T) {
+	params := DefaultParams()
+
+	// Default behavior hashes empty passwords for backward compatibility
+	for _, password := range [][]byte{nil, {}} {
+		if _, err := GenerateFromPassword(password, params); err != nil {
+			t.Errorf("expected empty password to hash by default, got %v", err)
+		}
+	}
+
+	params.RejectEmptyPassword = true
+	for _, password := range [][]byte{nil, {}} {
+		if _, err := GenerateFromPassword(password, params); err != ErrEmptyPassword {
+			t.Errorf("expected %v, got %v", ErrEmptyPassword, err)
+		}
+	}
+
+	hash, err := GenerateFromPassword([]byte("password"), params)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := CompareHashAndPassword(hash, []byte("password")); err != nil {
+		t.Error("expected password and hash to match")
+	}
+}
+
+func TestCompareHashAndPasswordTrusted(t *testing.T) {
+	// Legacy hash with Time above MaxTime, as if the limit were lowered after creation
+	salt := []byte("0123456789abcdef")
+	digest := argon2.IDKey([]byte("password"), salt, MaxTime+1, MinMemory, 1, 32)
+	hash := []byte(fmt.Sprintf("$argon2id$v=19$m=%d,t=%d,p=1$%s$%s", MinMemory, MaxTime+1,
+		base64.RawStdEncoding.EncodeToString(salt), base64.RawStdEncoding.EncodeToString(digest)))
+
+	if err := CompareHashAndPassword(hash, []byte("password")); err != ErrHashTooExpensive {
+		t.Errorf("expected %v for untrusted hash, got %v", ErrHashTooExpensive, err)
+	}
+
+	if err := CompareHashAndPasswordTrusted(hash, []byte("password")); err != nil {
+		t.Errorf("expected trusted hash to verify, got %v", err)
+	}
+	if err := CompareHashAndPasswordTrusted(hash, []byte("wrong")); err != ErrMismatchedHashAndPassword {
+		t.Errorf("expected %v, got %v", ErrMismatchedHashAndPassword, err)
+	}
+
+	// Hashes within the limits verify either way
+	hash, err := GenerateFromPassword([]byte("password"), &Params{Time: 1, Memory: MinMemory, Threads: 1, KeyLen: 32})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := CompareHashAndPasswordTrusted(hash, []byte("password")); err != nil {
+		t.Errorf("expected hash to verify, got %v", err)
+	}
+}
+
+func TestGenerateFromPasswordWithUsedParams(t *testing.T) {
+	hash, used, err := GenerateFromPasswordWithUsedParams([]byte("password"), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if *used != *DefaultParams() {
+		t.Errorf("expected default params, got %+v", used)
+	}
+
+	extracted, err := ExtractParams(hash)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if *extracted != *used {
+		t.Errorf("used params %+v do not match hash params %+v", used, extracted)
+	}
+
+	params := &Params{Time: 2, Memory: 1024, Threads: 1, KeyLen: 16}
+	_, used, err = GenerateFromPasswordWithUsedParams([]byte("password"), params)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if *used != *params {
+		t.Errorf("expected %+v, got %+v", params, used)
+	}
+
+	// The returned params must not alias the caller's
+	used.Time = 10
+	if params.Time != 2 {
+		t.Error("expected used params to be a copy")
+	}
+
+	if _, _, err := GenerateFromPasswordWithUsedParams([]byte("password"), &Params{}); err == nil {
+		t.Error("expected error for invalid params")
+	}
+}
+
+func TestHexEncodedSaltAndHash(t *testing.T) {
+	// Interop vector: digest from the reference implementation (libargon2
+	// 20171227, argon2id_hash_raw with t=2, m=1024, p=1, password
+	// "password", salt "0123456789abcdef"), whose argon2 CLI prints it in hex
+	// on its "Hash:" line; crypt(3)-style tools emit the same bytes as
+	// uppercase hex segments instead of base64
+	vector := "$argon2id$v=19$m=1024,t=2,p=1$30313233343536373839616263646566$FBB749B873E2A2F1DA56305BBE809193F2CC30AF6D2EF5FA09D69D9AA0A14D7B"
+
+	if err := CompareHashAndPassword([]byte(vector), []byte("password")); err != nil {
+		t.Errorf("expected uppercase hex hash to verify, got %v", err)
+	}
+	lower := strings.ToLower(vector)
+	if err := CompareHashAndPassword([]byte(lower), []byte("password")); err != nil {
+		t.Errorf("expected lowercase hex hash to verify, got %v", err)
+	}
+	if err := CompareHashAndPassword([]byte(vector), []byte("wrong")); err != ErrMismatchedHashAndPassword {
+		t.Errorf("expected %v, got %v", ErrMismatchedHashAndPassword, err)
+	}
+
+	// Invalid in both alphabets
+	if err := CompareHashAndPassword([]byte("$argon2id$v=19$m=1024,t=2,p=1$!!!!$!!!!!!!!!!!!!!!!"), []byte("password")); err != ErrInvalidHash {
+		t.Errorf("expected %v, got %v", ErrInvalidHash, err)
+	}
+
+	// Odd-length hex, and a hex salt next to a base64 digest
+	for _, bad := range []string{
+		"$argon2id$v=19$m=1024,t=2,p=1$3031323334353637383961626364656$FBB749B873E2A2F1DA56305BBE809193F2CC30AF6D2EF5FA09D69D9AA0A14D7B",
+		"$argon2id$v=19$m=1024,t=2,p=1$30313233343536373839616263646566$FBB749B873E2A2F1DA56305BBE809193F2CC30AF6D2EF5FA09D69D9AA0A14D7",
+		"$argon2id$v=19$m=1024,t=2,p=1$30313233343536373839616263646566$+7dJuHPiovHaVjBbvoCRk/LMMK9tLvX6CdadmqChTXs",
+	} {
+		if err := CompareHashAndPassword([]byte(bad), []byte("password")); err != ErrInvalidHash {
+			t.Errorf("%s: expected %v, got %v", bad, ErrInvalidHash, err)
+		}
+	}
+}
+
+func TestHashWithoutVersion(t *testing.T) {
+	// Interop vector: libsodium-style crypto_pwhash_str output without the
+	// v= segment (libargon2, t=2, m=1024, p=1, salt "somesaltsomesalt",
+	// password "correct horse battery staple")
+	vector := "$argon2id$m=1024,t=2,p=1$c29tZXNhbHRzb21lc2FsdA$Jl9aMRiFdmA52uqiivDqYVot6fVz3erNUlWPoxyg46A"
+	password := []byte("correct horse battery staple")
+
+	if err := CompareHashAndPassword([]byte(vector), password); err != nil {
+		t.Errorf("expected %s to verify, got %v", vector, err)
+	}
+	if err := CompareHashAndPassword([]byte(vector), []byte("wrong")); err != ErrMismatchedHashAndPassword {
+		t.Errorf("expected %v, got %v", ErrMismatchedHashAndPassword, err)
+	}
+
+	params, err := ExtractParams([]byte(vector))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if params.Time != 2 || params.Memory != 1024 || params.Threads != 1 || params.KeyLen != 32 {
+		t.Errorf("unexpected params %+v", params)
+	}
+
+	// Four parts are still invalid
+	if err := CompareHashAndPassword([]byte("$argon2id$m=1024,t=2,p=1$c29tZXNhbHRzb21lc2FsdA"), password); err != ErrInvalidHash {
+		t.Errorf("expected %v, got %v", ErrInvalidHash, err)
+	}
+}
+
+func BenchmarkDecodeHash(b *testing.B) {
+	hash := "$argon2id$v=19$m=65536,t=3,p=2$mFe3kxhovyEByvwnUtr0ow$nU9AqnoPfzMOQhCHa9BDrQ+4bSfj69jgtvGu/2McCxU"
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if _, _, _, err := decodeHash(hash, nil); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func TestRehashIfNeeded(t *testing.T) {
+	weak := &Params{Time: 1, Memory: 1024, Threads: 1, KeyLen: 32}
+	strong := &Params{Time: 2, Memory: 2048, Threads: 1, KeyLen: 32}
+
+	hash, err := GenerateFromPassword([]byte("password"), weak)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// No-op: the hash already meets the desired params
+	same, err := RehashIfNeeded(hash, []byte("password"), weak)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(same) != string(hash) {
+		t.Error("expected original hash when no upgrade is needed")
+	}
+
+	// Upgrade: a stronger hash that still verifies
+	upgraded, err := RehashIfNeeded(hash, []byte("password"), strong)
+	if err != nil {
+		t.Fatal(err)
+	}
+	params, err := ExtractParams(upgraded)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if params.Time != strong.Time || params.Memory != strong.Memory {
+		t.Errorf("expected upgraded params %+v, got %+v", strong, params)
+	}
+	if err := CompareHashAndPassword(upgraded, []byte("password")); err != nil {
+		t.Errorf("expected upgraded hash to verify, got %v", err)
+	}
+
+	// Wrong password: no hash is produced
+	result, err := RehashIfNeeded(hash, []byte("wrong"), strong)
+	if err != ErrMismatchedHashAndPassword {
+		t.Errorf("expected %v, got %v", ErrMismatchedHashAndPassword, err)
+	}
+	if result != nil {
+		t.Error("expected no hash for a wrong password")
+	}
+}
+
+func TestOnRehash(t *testing.T) {
+	weak := &Params{Time: 1, Memory: 1024, Threads: 1, KeyLen: 32}
+	strong := &Params{Time: 2, Memory: 2048, Threads: 1, KeyLen: 32}
+	hash, err := GenerateFromPassword([]byte("password"), weak)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	type upgrade struct{ old, new Params }
+	var upgrades []upgrade
+	OnRehash = func(oldParams, newParams *Params) {
+		upgrades = append(upgrades, upgrade{*oldParams, *newParams})
+	}
+	t.Cleanup(func() { OnRehash = nil })
+
+	// No-ops and failures do not fire
+	if _, err := RehashIfNeeded(hash, []byte("password"), weak); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := RehashIfNeeded(hash, []byte("wrong"), strong); err != ErrMismatchedHashAndPassword {
+		t.Fatalf("expected %v, got %v", ErrMismatchedHashAndPassword, err)
+	}
+	if _, err := NewHasher(weak).CompareAndUpgrade(hash, []byte("password")); err != nil {
+		t.Fatal(err)
+	}
+	if len(upgrades) != 0 {
+		t.Fatalf("expected no upgrades, got %+v", upgrades)
+	}
+
+	// Each upgrade fires once
+	if _, err := RehashIfNeeded(hash, []byte("password"), strong); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := NewHasher(strong).CompareAndUpgrade(hash, []byte("password")); err != nil {
+		t.Fatal(err)
+	}
+	if len(upgrades) != 2 {
+		t.Fatalf("expected 2 upgrades, got %+v", upgrades)
+	}
+	for _, u := range upgrades {
+		if u.old.Time != weak.Time || u.old.Memory != weak.Memory || u.new.Time != strong.Time || u.new.Memory != strong.Memory {
+			t.Errorf("unexpected upgrade %+v", u)
+		}
+	}
+}
+
+func TestResalt(t *testing.T) {
+	params := &Params{Time: 2, Memory: 2048, Threads: 1, KeyLen: 24}
+	hash, err := GenerateFromPassword([]byte("password"), params)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	resalted, err := Resalt(hash, []byte("password"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := CompareHashAndPassword(resalted, []byte("password")); err != nil {
+		t.Errorf("expected resalted hash to verify, got %v", err)
+	}
+
+	got, err := ExtractParams(resalted)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.Time != params.Time || got.Memory != params.Memory || got.Threads != params.Threads || got.KeyLen != params.KeyLen {
+		t.Errorf("expected params %+v, got %+v", params, got)
+	}
+
+	oldSalt, _ := ExtractSalt(hash)
+	newSalt, _ := ExtractSalt(resalted)
+	if bytes.Equal(oldSalt, newSalt) {
+		t.Error("expected a new salt")
+	}
+
+	result, err := Resalt(hash, []byte("wrong"))
+	if err != ErrMismatchedHashAndPassword || result != nil {
+		t.Errorf("expected %v and no hash, got %v", ErrMismatchedHashAndPassword, err)
+	}
+}
+
+func TestCompareHashAndPasswordURLEncoded(t *testing.T) {
+	var hash []byte
+	for hash == nil || !bytes.Contains(hash, []byte("+")) {
+		var err error
+		if hash, err = GenerateFromPassword([]byte("password"), &Params{Time: 1, Memory: 1024, Threads: 1, KeyLen: 32}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	for _, encoded := range []string{
+		url.QueryEscape(string(hash)),
+		url.PathEscape(string(hash)),
+		strings.ReplaceAll(string(hash), "$", "%24"),
+		string(hash),
+	} {
+		if err := CompareHashAndPasswordURLEncoded([]byte(encoded), []byte("password")); err != nil {
+			t.Errorf("expected %s to verify, got %v", encoded, err)
+		}
+		if err := CompareHashAndPasswordURLEncoded([]byte(encoded), []byte("wrong")); err != ErrMismatchedHashAndPassword {
+			t.Errorf("expected %v for %s, got %v", ErrMismatchedHashAndPassword, encoded, err)
+		}
+	}
+
+	if !strings.Contains(url.QueryEscape(string(hash)), "%24") {
+		t.Fatal("expected '$' to be percent-encoded")
+	}
+	if err := CompareHashAndPasswordURLEncoded([]byte("%24argon2id%2"), []byte("password")); err != ErrInvalidHash {
+		t.Errorf("expected %v for a malformed escape, got %v", ErrInvalidHash, err)
+	}
+}
+
+func TestCompareHashAndPasswordExpect(t *testing.T) {
+	policy := &Params{Time: 2, Memory: 1024, Threads: 1, KeyLen: 32}
+	hash, err := GenerateFromPassword([]byte("password"), policy)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := CompareHashAndPasswordExpect(hash, []byte("password"), policy); err != nil {
+		t.Errorf("expected matching params to verify, got %v", err)
+	}
+	if err := CompareHashAndPasswordExpect(hash, []byte("wrong"), policy); err != ErrMismatchedHashAndPassword {
+		t.Errorf("expected %v, got %v", ErrMismatchedHashAndPassword, err)
+	}
+
+	for _, expected := range []*Params{
+		{Time: 3, Memory: 1024, Threads: 1, KeyLen: 32},
+		{Time: 2, Memory: 2048, Threads: 1, KeyLen: 32},
+		{Time: 2, Memory: 1024, Threads: 2, KeyLen: 32},
+		{Time: 2, Memory: 1024, Threads: 1, KeyLen: 16},
+		{Time: 1, Memory: 1024, Threads: 1, KeyLen: 32}, // weaker policy also differs
+	} {
+		if err := CompareHashAndPasswordExpect(hash, []byte("password"), expected); err != ErrUnexpectedParams {
+			t.Errorf("expected %v for %+v, got %v", ErrUnexpectedParams, expected, err)
+		}
+	}
+
+	if err := CompareHashAndPasswordExpect([]byte("garbage"), []byte("password"), policy); err != ErrHashTooShort {
+		t.Errorf("expected %v, got %v", ErrHashTooShort, err)
+	}
+}
+
+func TestVersionNotation(t *testing.T) {
+	salt := []byte("0123456789abcdef")
+	digest := argon2.IDKey([]byte("password"), salt, 1, 1024, 1, 32)
+	encode := func(version string) []byte {
+		return []byte(fmt.Sprintf("$argon2id$%s$m=1024,t=1,p=1$%s$%s", version,
+			base64.RawStdEncoding.EncodeToString(salt), base64.RawStdEncoding.EncodeToString(digest)))
+	}
+
+	tests := []struct {
+		version string
+		wantErr error
+	}{
+		{"v=19", nil},
+		{"v=0x13", nil},
+		{"v=0x10", ErrIncompatibleVersion},
+		{"v=16", ErrIncompatibleVersion},
+		{"v=023", ErrIncompatibleVersion}, // octal is not accepted
+		{"v=0x", ErrIncompatibleVersion},
+		{"v=", ErrIncompatibleVersion},
+		{"19", ErrIncompatibleVersion},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.version, func(t *testing.T) {
+			if err := CompareHashAndPassword(encode(tt.version), []byte("password")); err != tt.wantErr {
+				t.Errorf("expected %v, got %v", tt.wantErr, err)
+			}
+		})
+	}
+
+	// Generation keeps emitting the canonical decimal version
+	hash, err := GenerateFromPassword([]byte("password"), &Params{Time: 1, Memory: 1024, Threads: 1, KeyLen: 32})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasPrefix(string(hash), "$argon2id$v=19$") {
+		t.Errorf("expected canonical v=19, got %q", hash)
+	}
+}
+
+func TestHexParams(t *testing.T) {
+	salt := []byte("0123456789abcdef")
+	digest := argon2.IDKey([]byte("password"), salt, 2, 1024, 1, 32)
+	encode := func(params string) []byte {
+		return []byte(fmt.Sprintf("$argon2id$v=19$%s$%s$%s", params,
+			base64.RawStdEncoding.EncodeToString(salt), base64.RawStdEncoding.EncodeToString(digest)))
+	}
+
+	for _, params := range []string{"m=1024,t=2,p=1", "m=0x400,t=0x2,p=0x1", "m=0x400,t=2,p=1"} {
+		if err := CompareHashAndPassword(encode(params), []byte("password")); err != nil {
+			t.Errorf("expected %s to verify, got %v", params, err)
+		}
+	}
+
+	extracted, err := ExtractParams(encode("m=0x10000,t=2,p=1"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if extracted.Memory != 65536 {
+		t.Errorf("expected m=0x10000 to equal m=65536, got %d", extracted.Memory)
+	}
+
+	for _, params := range []string{
+		"m=0x,t=2,p=1",
+		"m=0X400,t=2,p=1",
+		"m=0o2000,t=2,p=1",
+		"m=0b10000000000,t=2,p=1",
+		"m=0x-400,t=2,p=1",
+		"m=0x4_00,t=2,p=1",
+		"m=0x100000000,t=2,p=1",
+		"m=1024,t=0xg,p=1",
+	} {
+		if _, err := ExtractParams(encode(params)); err != ErrInvalidHash {
+			t.Errorf("expected %v for %s, got %v", ErrInvalidHash, params, err)
+		}
+	}
+
+	// Generation keeps emitting decimal
+	hash, err := GenerateFromPassword([]byte("password"), &Params{Time: 2, Memory: 65536, Threads: 1, KeyLen: 32})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(hash), "$m=65536,t=2,p=1$") {
+		t.Errorf("expected decimal params, got %q", hash)
+	}
+}
+
+func TestUnknownParamsRoundTrip(t *testing.T) {
+	params := &Params{Time: 1, Memory: 1024, Threads: 1, KeyLen: 32}
+	hash, err := GenerateFromPassword([]byte("password"), params)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Simulate a newer encoder adding a parameter
+	parts := strings.Split(string(hash), "$")
+	parts[3] += ",x=42"
+	future := []byte(strings.Join(parts, "$"))
+
+	if err := CompareHashAndPassword(future, []byte("password")); err != nil {
+		t.Fatalf("expected hash with unknown param to verify, got %v", err)
+	}
+
+	decoded, err := ExtractParams(future)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if decoded.Extra != "x=42" {
+		t.Errorf("expected Extra %q, got %q", "x=42", decoded.Extra)
+	}
+
+	reencoded, err := GenerateFromPassword([]byte("password"), decoded)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := strings.Split(string(reencoded), "$")[3]; got != "m=1024,t=1,p=1,x=42" {
+		t.Errorf("expected params segment %q, got %q", "m=1024,t=1,p=1,x=42", got)
+	}
+
+	// Unknown params may come first; order is preserved
+	parts[3] = "y=7,m=1024,x=42,t=1,p=1"
+	decoded, err = ExtractParams([]byte(strings.Join(parts, "$")))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if decoded.Extra != "y=7,x=42" {
+		t.Errorf("expected Extra %q, got %q", "y=7,x=42", decoded.Extra)
+	}
+
+	for _, extra := range []string{"x", "x=", "=1", "x=1=2", "m=1", "x=1,", "x=$"} {
+		params := &Params{Time: 1, Memory: 1024, Threads: 1, KeyLen: 32, Extra: extra}
+		if _, err := GenerateFromPassword([]byte("password"), params); err == nil {
+			t.Errorf("expected error for Extra %q", extra)
+		}
+	}
+}
+
+func TestAssociatedDataRoundTrip(t *testing.T) {
+	params := &Params{Time: 1, Memory: 1024, Threads: 1, KeyLen: 32, Extra: "data=c29tZWRhdGE"}
+	hash, err := GenerateFromPassword([]byte("password"), params)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := strings.Split(string(hash), "$")[3]; got != "m=1024,t=1,p=1,data=c29tZWRhdGE" {
+		t.Errorf("expected params segment %q, got %q", "m=1024,t=1,p=1,data=c29tZWRhdGE", got)
+	}
+
+	decoded, err := ExtractParams(hash)
+	if err != nil {
+		t.Fatal(err)
+	}
+	data, err := decoded.AssociatedData()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(data) != "somedata" {
+		t.Errorf("expected associated data %q, got %q", "somedata", data)
+	}
+
+	reencoded, err := Resalt(hash, []byte("password"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(reencoded), ",data=c29tZWRhdGE$") {
+		t.Errorf("expected data parameter to be preserved in %s", reencoded)
+	}
+
+	for _, extra := range []string{"", "x=42", postHashMarker} {
+		data, err := (&Params{Extra: extra}).AssociatedData()
+		if err != nil || data != nil {
+			t.Errorf("Extra %q: expected no associated data, got %q, %v", extra, data, err)
+		}
+	}
+	if _, err := (&Params{Extra: "data=c29tZWRhdGE="}).AssociatedData(); err == nil {
+		t.Error("expected error for padded data parameter")
+	}
+}
+
+func TestKeyLenParam(t *testing.T) {
+	params := &Params{Time: 1, Memory: 1024, Threads: 1, KeyLen: 32}
+	hash, err := GenerateFromPassword([]byte("password"), params)
+	if err != nil {
+		t.Fatal(err)
+	}
+	withKeyLen := func(l string) []byte {
+		parts := strings.Split(string(hash), "$")
+		parts[3] += ",l=" + l
+		return []byte(strings.Join(parts, "$"))
+	}
+
+	consistent := withKeyLen("32")
+	if err := CompareHashAndPassword(consistent, []byte("password")); err != nil {
+		t.Errorf("expected hash with matching l= to verify, got %v", err)
+	}
+	decoded, err := ExtractParams(consistent)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if decoded.KeyLen != 32 || decoded.Extra != "l=32" {
+		t.Errorf("expected KeyLen 32 and Extra l=32, got %d and %q", decoded.KeyLen, decoded.Extra)
+	}
+
+	for _, l := range []string{"16", "64", "x", "0x20a"} {
+		if _, err := ExtractParams(withKeyLen(l)); err != ErrInvalidHash {
+			t.Errorf("l=%s: expected ErrInvalidHash, got %v", l, err)
+		}
+	}
+	if _, err := ExtractParams(withKeyLen("0x20")); err != nil {
+		t.Errorf("expected hex l= to decode like other parameters, got %v", err)
+	}
+
+	// Re-encoding with another KeyLen keeps l= consistent
+	decoded.KeyLen = 16
+	reencoded, err := GenerateFromPassword([]byte("password"), decoded)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(reencoded), ",l=16$") {
+		t.Errorf("expected l=16 in %s", reencoded)
+	}
+	if err := CompareHashAndPassword(reencoded, []byte("password")); err != nil {
+		t.Errorf("expected re-encoded hash to verify, got %v", err)
+	}
+}
+
+func TestIsArgon2idHash(t *testing.T) {
+	hash, err := GenerateFromPassword([]byte("password"), &Params{Time: 1, Memory: 1024, Threads: 1, KeyLen: 32})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !IsArgon2idHash(hash) {
+		t.Errorf("expected %s to be recognized", hash)
+	}
+
+	for _, hash := range []string{
+		"",
+		"$2a$10$N9qo8uLOickgx2ZMRZoMyeIjZAgcfl7p92ldGxad68LJZdL17lhWy",
+		"$argon2i$v=19$m=65536,t=1,p=2$mFe3kxhovyEByvwnUtr0ow$nU9AqnoPfzMOQhCHa9BDrQ+4bSfj69jgtvGu/2McCxU",
+		"$argon2id$v=19$m=65536,t=3,p=2$mFe3kxhovyEByvwnUtr0ow",
+	} {
+		if IsArgon2idHash([]byte(hash)) {
+			t.Errorf("expected %q not to be recognized", hash)
+		}
+	}
+}
+
+func TestTruncatedDigest(t *testing.T) {
+	hash, err := GenerateFromPassword([]byte("password"), &Params{Time: 1, Memory: 1024, Threads: 1, KeyLen: 32})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// A column sized for a shorter hash keeps only the first digest bytes
+	i := strings.LastIndexByte(string(hash), '$')
+	truncated := hash[:i+4]
+	if err := CompareHashAndPassword(truncated, []byte("password")); err != ErrTruncatedDigest {
+		t.Errorf("expected %v, got %v", ErrTruncatedDigest, err)
+	}
+
+	oversized := string(hash[:i+1]) + base64.RawStdEncoding.EncodeToString(make([]byte, MaxKeyLen+1))
+	if _, err := ExtractParams([]byte(oversized)); err != ErrTruncatedDigest {
+		t.Errorf("expected %v, got %v", ErrTruncatedDigest, err)
+	}
+
+	minimal := string(hash[:i+1]) + base64.RawStdEncoding.EncodeToString(make([]byte, MinKeyLen))
+	if _, err := ExtractParams([]byte(minimal)); err != nil {
+		t.Errorf("expected a %d-byte digest to decode, got %v", MinKeyLen, err)
+	}
+}
+
+func TestInvalidThreads(t *testing.T) {
+	const tail = "$PD90ckFJR9sRjzSrUtbKlQ$FKYaq32UmwYMabKEcCSOsy0Z9unTzMTPV8mKfD5q/bM"
+	for _, p := range []string{"0", "256", "99999999999999999999"} {
+		hash := "$argon2id$v=19$m=1024,t=2,p=" + p + tail
+		_, err := ExtractParams([]byte(hash))
+		if err != ErrInvalidThreads {
+			t.Errorf("expected %v for p=%s, got %v", ErrInvalidThreads, p, err)
+		}
+		if !errors.Is(err, ErrInvalidHash) {
+			t.Errorf("expected p=%s error to wrap %v", p, ErrInvalidHash)
+		}
+	}
+
+	// Other malformations are not reported as a threads error
+	if _, err := ExtractParams([]byte("$argon2id$v=19$m=1024,t=2,p=x" + tail)); err != ErrInvalidHash {
+		t.Errorf("expected %v, got %v", ErrInvalidHash, err)
+	}
+	if _, err := ExtractParams([]byte("$argon2id$v=19$m=1024,t=2,p=255" + tail)); err != nil {
+		t.Errorf("expected p=255 to decode, got %v", err)
+	}
+}
+
+func TestEmptySegments(t *testing.T) {
+	const (
+		params = "$argon2id$v=19$m=1024,t=2,p=1"
+		salt   = "PD90ckFJR9sRjzSrUtbKlQ"
+		digest = "FKYaq32UmwYMabKEcCSOsy0Z9unTzMTPV8mKfD5q/bM"
+	)
+	tests := []struct {
+		hash string
+		want error
+	}{
+		{params + "$$" + digest, ErrEmptySalt},
+		{"$argon2id$m=1024,t=2,p=1$$" + digest, ErrEmptySalt},
+		{params + "$" + salt + "$", ErrEmptyDigest},
+		{params + "$" + salt + "$$", ErrEmptyDigest},
+		{"$argon2id$m=1024,t=2,p=1$" + salt + "$", ErrEmptyDigest},
+		{params + "$$", ErrEmptySalt},
+	}
+	for _, tt := range tests {
+		_, err := ExtractParams([]byte(tt.hash))
+		if err != tt.want {
+			t.Errorf("%s: expected %v, got %v", tt.hash, tt.want, err)
+		}
+		if !errors.Is(err, ErrInvalidHash) {
+			t.Errorf("%s: expected error to wrap %v", tt.hash, ErrInvalidHash)
+		}
+	}
+
+	// A trailing '$' after a complete hash is still tolerated, with or
+	// without the version segment
+	hash := params + "$" + salt + "$" + digest + "$"
+	if _, err := ExtractParams([]byte(hash)); err != nil {
+		t.Errorf("%s: expected hash to decode, got %v", hash, err)
+	}
+	hash = "$argon2id$m=1024,t=2,p=1$" + salt + "$" + digest + "$"
+	if _, err := ExtractParams([]byte(hash)); err != nil {
+		t.Errorf("%s: expected hash to decode, got %v", hash, err)
+	}
+}
+
+func TestSchemeLabelPrefix(t *testing.T) {
+	hash, err := GenerateFromPassword([]byte("password"), &Params{Time: 1, Memory: 1024, Threads: 1, KeyLen: 32})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.HasPrefix(string(hash), "argon2id:") {
+		t.Errorf("expected generated hash without a scheme label, got %s", hash)
+	}
+
+	for _, stored := range []string{string(hash), "argon2id:" + string(hash), "ARGON2ID:" + string(hash)} {
+		if err := CompareHashAndPassword([]byte(stored), []byte("password")); err != nil {
+			t.Errorf("expected %s to verify, got %v", stored, err)
+		}
+		if err := CompareHashAndPassword([]byte(stored), []byte("wrong")); err != ErrMismatchedHashAndPassword {
+			t.Errorf("expected %v for %s, got %v", ErrMismatchedHashAndPassword, stored, err)
+		}
+	}
+
+	raw := NewHasher(&Params{Time: 1, Memory: 1024, Threads: 1, KeyLen: 32, Encoding: EncodingRaw})
+	hash, err = raw.GenerateFromPassword([]byte("password"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := raw.CompareHashAndPassword([]byte("argon2id:"+string(hash)), []byte("password")); err != nil {
+		t.Errorf("expected labeled raw hash to verify, got %v", err)
+	}
+	if err := raw.CompareHashAndPassword([]byte("bcrypt:"+string(hash)), []byte("password")); err == nil {
+		t.Error("expected raw hash with another scheme label to be rejected")
+	}
+}
+
+func TestZeroThreadsRejected(t *testing.T) {
+	// A buggy encoder's p=0 hash, with the digest argon2.IDKey computes after
+	// silently raising threads to 1
+	salt := []byte("0123456789abcdef")
+	digest := argon2.IDKey([]byte("password"), salt, 1, 1024, 1, 32)
+	hash := []byte(fmt.Sprintf("$argon2id$v=19$m=1024,t=1,p=0$%s$%s",
+		base64.RawStdEncoding.EncodeToString(salt), base64.RawStdEncoding.EncodeToString(digest)))
+
+	if err := CompareHashAndPassword(hash, []byte("password")); !errors.Is(err, ErrInvalidHash) {
+		t.Errorf("expected %v, got %v", ErrInvalidHash, err)
+	}
+	if err := CompareHashAndPasswordTrusted(hash, []byte("password")); !errors.Is(err, ErrInvalidHash) {
+		t.Errorf("expected %v from the trusted variant, got %v", ErrInvalidHash, err)
+	}
+	if IsArgon2idHash(hash) {
+		t.Error("expected p=0 hash not to be recognized")
+	}
+	if _, err := GenerateFromPassword([]byte("password"), &Params{Time: 1, Memory: 1024, Threads: 0, KeyLen: 32}); err == nil {
+		t.Error("expected generation with zero threads to fail too")
+	}
+}
+
+func TestLineEndings(t *testing.T) {
+	params := &Params{Time: 1, Memory: 1024, Threads: 1, KeyLen: 32}
+	phc, err := GenerateFromPassword([]byte("password"), params)
+	if err != nil {
+		t.Fatal(err)
+	}
+	raw, err := NewHasher(&Params{Time: 1, Memory: 1024, Threads: 1, KeyLen: 32, Encoding: EncodingRaw}).GenerateFromPassword([]byte("password"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	salt := []byte("0123456789abcdef")
+	hexHash := fmt.Sprintf("$argon2id$v=19$m=1024,t=1,p=1$%x$%x", salt, argon2.IDKey([]byte("password"), salt, 1, 1024, 1, 32))
+
+	check := func(name string, hash []byte) {
+		t.Helper()
+		for _, ending := range []string{"\r\n", "\n", "\r"} {
+			h := NewHasher(params)
+			if bytes.IndexByte(hash, '.') >= 0 {
+				h.Params = &Params{Time: 1, Memory: 1024, Threads: 1, KeyLen: 32, Encoding: EncodingRaw}
+			}
+			if err := h.CompareHashAndPassword(append(append([]byte{}, hash...), ending...), []byte("password")); err != nil {
+				t.Errorf("%s with %q: expected to verify, got %v", name, ending, err)
+			}
+		}
+	}
+	check("PHC", phc)
+	check("hex PHC", []byte(hexHash))
+	check("raw", raw)
+
+	// Only one line ending is removed
+	if err := CompareHashAndPassword(append([]byte(hexHash), "\r\n\r\n"...), []byte("password")); err == nil {
+		t.Error("expected two line endings to be rejected")
+	}
+
+	ConstantTimeDecode = true
+	t.Cleanup(func() { ConstantTimeDecode = false })
+	check("constant-time PHC", phc)
+}
+
+func TestMixedPaddingSegments(t *testing.T) {
+	salt := []byte("0123456789abcdef")
+	digest := argon2.IDKey([]byte("password"), salt, 1, 1024, 1, 32)
+	raw := base64.RawStdEncoding
+	padded := base64.StdEncoding
+
+	tests := []struct {
+		name string
+		hash string
+	}{
+		{"padded salt, raw digest", fmt.Sprintf("$argon2id$v=19$m=1024,t=1,p=1$%s$%s", padded.EncodeToString(salt), raw.EncodeToString(digest))},
+		{"raw salt, padded digest", fmt.Sprintf("$argon2id$v=19$m=1024,t=1,p=1$%s$%s", raw.EncodeToString(salt), padded.EncodeToString(digest))},
+		{"both padded", fmt.Sprintf("$argon2id$v=19$m=1024,t=1,p=1$%s$%s", padded.EncodeToString(salt), padded.EncodeToString(digest))},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := CompareHashAndPassword([]byte(tt.hash), []byte("password")); err != nil {
+				t.Errorf("expected %s to verify, got %v", tt.hash, err)
+			}
+		})
+	}
+
+	// Padding in the middle of a segment is still malformed
+	bad := fmt.Sprintf("$argon2id$v=19$m=1024,t=1,p=1$%s$%s", "MDEy=NDU2Nzg5YWJjZGVm", raw.EncodeToString(digest))
+	if err := CompareHashAndPassword([]byte(bad), []byte("password")); err != ErrInvalidHash {
+		t.Errorf("expected %v, got %v", ErrInvalidHash, err)
+	}
+}
+
+func TestExtraBoundaryDelimiters(t *testing.T) {
+	hash, err := GenerateFromPassword([]byte("password"), &Params{Time: 1, Memory: 1024, Threads: 1, KeyLen: 32})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tolerated := []string{
+		"$" + string(hash),
+		string(hash) + "$",
+		"$" + string(hash) + "$",
+	}
+	for _, h := range tolerated {
+		if err := CompareHashAndPassword([]byte(h), []byte("password")); err != nil {
+			t.Errorf("expected %q to verify, got %v", h, err)
+		}
+	}
+
+	malformed := []string{
+		"$$" + string(hash),
+		string(hash) + "$$",
+		strings.Replace(string(hash), "$v=19$", "$$v=19$", 1),
+		strings.Replace(string(hash), "$v=19$", "$v=19$$", 1),
+	}
+	for _, h := range malformed {
+		if err := CompareHashAndPassword([]byte(h), []byte("password")); err != ErrInvalidHash {
+			t.Errorf("expected %v for %q, got %v", ErrInvalidHash, h, err)
+		}
+	}
+}
+
+func TestCompareHashAndPasswordTimed(t *testing.T) {
+	hash, err := GenerateFromPassword([]byte("password"), &Params{Time: 1, Memory: 1024, Threads: 1, KeyLen: 32})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	now := time.Unix(0, 0)
+	SetTimeSource(func() time.Time {
+		now = now.Add(40 * time.Millisecond)
+		return now
+	})
+	t.Cleanup(func() { SetTimeSource(nil) })
+
+	elapsed, err := CompareHashAndPasswordTimed(hash, []byte("password"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if elapsed != 40*time.Millisecond {
+		t.Errorf("expected 40ms, got %s", elapsed)
+	}
+
+	elapsed, err = CompareHashAndPasswordTimed(hash, []byte("wrong"))
+	if err != ErrMismatchedHashAndPassword {
+		t.Errorf("expected %v, got %v", ErrMismatchedHashAndPassword, err)
+	}
+	if elapsed != 40*time.Millisecond {
+		t.Errorf("expected a mismatch to be timed too, got %s", elapsed)
+	}
+
+	elapsed, err = CompareHashAndPasswordTimed([]byte("not a hash"), []byte("password"))
+	if err == nil || elapsed != 0 {
+		t.Errorf("expected an error and no duration for a malformed hash, got %s, %v", elapsed, err)
+	}
+}
+
+func TestStrictDecode(t *testing.T) {
+	salt := []byte("0123456789abcdef")
+	digest := argon2.IDKey([]byte("password"), salt, 1, 4, 1, 32)
+	// m=4 is below MinMemory but cheap enough to verify
+	belowMin := []byte(fmt.Sprintf("$argon2id$v=19$m=4,t=1,p=1$%s$%s",
+		base64.RawStdEncoding.EncodeToString(salt), base64.RawStdEncoding.EncodeToString(digest)))
+	aboveMax := []byte(fmt.Sprintf("$argon2id$v=19$m=1024,t=%d,p=1$%s$%s", MaxTime+1,
+		base64.RawStdEncoding.EncodeToString(salt), base64.RawStdEncoding.EncodeToString(digest)))
+
+	if err := CompareHashAndPasswordTrusted(belowMin, []byte("password")); err != nil {
+		t.Fatalf("expected out-of-policy hash to verify without StrictDecode, got %v", err)
+	}
+	if _, err := ExtractParams(aboveMax); err != nil {
+		t.Fatalf("expected out-of-policy hash to decode without StrictDecode, got %v", err)
+	}
+
+	StrictDecode = true
+	t.Cleanup(func() { StrictDecode = false })
+
+	if err := CompareHashAndPasswordTrusted(belowMin, []byte("password")); err != ErrInvalidHash {
+		t.Errorf("expected %v below the minimums, got %v", ErrInvalidHash, err)
+	}
+	if _, err := ExtractParams(aboveMax); err != ErrInvalidHash {
+		t.Errorf("expected %v above the maximums, got %v", ErrInvalidHash, err)
+	}
+
+	hash, err := GenerateFromPassword([]byte("password"), &Params{Time: 1, Memory: 1024, Threads: 1, KeyLen: 32})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := CompareHashAndPassword(hash, []byte("password")); err != nil {
+		t.Errorf("expected in-policy hash to verify, got %v", err)
+	}
+}
+
+func TestOnNonstandardSalt(t *testing.T) {
+	encode := func(salt []byte) []byte {
+		digest := argon2.IDKey([]byte("password"), salt, 1, 1024, 1, 32)
+		return []byte(fmt.Sprintf("$argon2id$v=19$m=1024,t=1,p=1$%s$%s",
+			base64.RawStdEncoding.EncodeToString(salt), base64.RawStdEncoding.EncodeToString(digest)))
+	}
+	short := encode([]byte("8bytesal"))
+	tooShort := encode([]byte("7bytesa"))
+	standard := encode([]byte("0123456789abcdef"))
+
+	if err := CompareHashAndPassword(short, []byte("password")); err != ErrInvalidHash {
+		t.Fatalf("expected %v without OnNonstandardSalt, got %v", ErrInvalidHash, err)
+	}
+
+	var reported []int
+	OnNonstandardSalt = func(length int) { reported = append(reported, length) }
+	t.Cleanup(func() { OnNonstandardSalt = nil })
+
+	if err := CompareHashAndPassword(short, []byte("password")); err != nil {
+		t.Errorf("expected 8-byte salt to verify, got %v", err)
+	}
+	if err := CompareHashAndPassword(short, []byte("wrong")); err != ErrMismatchedHashAndPassword {
+		t.Errorf("expected %v, got %v", ErrMismatchedHashAndPassword, err)
+	}
+	if err := CompareHashAndPassword(standard, []byte("password")); err != nil {
+		t.Errorf("expected standard salt to verify, got %v", err)
+	}
+	if err := CompareHashAndPassword(tooShort, []byte("password")); err != ErrInvalidHash {
+		t.Errorf("expected %v below MinSaltLen, got %v", ErrInvalidHash, err)
+	}
+	if !slices.Equal(reported, []int{8, 8}) {
+		t.Errorf("expected two reports of length 8, got %v", reported)
+	}
+}
+
+func TestRejectWeakSalt(t *testing.T) {
+	encode := func(salt []byte) []byte {
+		digest := argon2.IDKey([]byte("password"), salt, 1, 1024, 1, 32)
+		return []byte(fmt.Sprintf("$argon2id$v=19$m=1024,t=1,p=1$%s$%s",
+			base64.RawStdEncoding.EncodeToString(salt), base64.RawStdEncoding.EncodeToString(digest)))
+	}
+	zero := encode(make([]byte, SaltLen))
+	repeated := encode(bytes.Repeat([]byte{0xab}, SaltLen))
+	normal := encode([]byte("0123456789abcdef"))
+
+	if !strings.Contains(string(zero), "$AAAAAAAAAAAAAAAAAAAAAA$") {
+		t.Fatalf("unexpected all-zero salt encoding %s", zero)
+	}
+	if err := CompareHashAndPassword(zero, []byte("password")); err != nil {
+		t.Fatalf("expected all-zero salt to verify without RejectWeakSalt, got %v", err)
+	}
+
+	RejectWeakSalt = true
+	t.Cleanup(func() { RejectWeakSalt = false })
+
+	for _, hash := range [][]byte{zero, repeated} {
+		if err := CompareHashAndPassword(hash, []byte("password")); err != ErrWeakSalt {
+			t.Errorf("expected %v for %s, got %v", ErrWeakSalt, hash, err)
+		}
+		if _, err := ExtractParams(hash); err != ErrWeakSalt {
+			t.Errorf("expected %v from ExtractParams, got %v", ErrWeakSalt, err)
+		}
+	}
+	if err := CompareHashAndPassword(normal, []byte("password")); err != nil {
+		t.Errorf("expected normal salt to verify, got %v", err)
+	}
+}
+
+func TestParamsEqual(t *testing.T) {
+	base := &Params{Time: 3, Memory: 1024, Threads: 2, KeyLen: 32}
+	same := &Params{Time: 3, Memory: 1024, Threads: 2, KeyLen: 32, Encoding: EncodingRaw, Extra: "x=1", RejectEmptyPassword: true}
+	if !base.Equal(same) || !same.Equal(base) {
+		t.Error("expected params differing only in encoding and options to be equal")
+	}
+	for _, other := range []*Params{
+		{Time: 4, Memory: 1024, Threads: 2, KeyLen: 32},
+		{Time: 3, Memory: 2048, Threads: 2, KeyLen: 32},
+		{Time: 3, Memory: 1024, Threads: 1, KeyLen: 32},
+		{Time: 3, Memory: 1024, Threads: 2, KeyLen: 16},
+		nil,
+	} {
+		if base.Equal(other) {
+			t.Errorf("expected %+v not to equal %+v", base, other)
+		}
+	}
+	var none *Params
+	if !none.Equal(nil) {
+		t.Error("expected nil params to be equal")
+	}
+}
+
+func TestIsDefaultParams(t *testing.T) {
+	defaultHash, err := GenerateFromPassword([]byte("password"), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	custom := DefaultParams()
+	custom.Time++
+	customHash, err := GenerateFromPassword([]byte("password"), custom)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if isDefault, err := IsDefaultParams(defaultHash); err != nil || !isDefault {
+		t.Errorf("expected default hash to report true, got %v, %v", isDefault, err)
+	}
+	if isDefault, err := IsDefaultParams(customHash); err != nil || isDefault {
+		t.Errorf("expected custom hash to report false, got %v, %v", isDefault, err)
+	}
+	if _, err := IsDefaultParams([]byte("garbage")); err == nil {
+		t.Error("expected error for an invalid hash")
+	}
+}
+
+func TestParamsClone(t *testing.T) {
+	original := &Params{Time: 3, Memory: 64 * 1024, Threads: 2, KeyLen: 32, Extra: "x=42"}
+	clone := original.Clone()
+	if clone == original {
+		t.Fatal("expected a distinct pointer")
+	}
+	if *clone != *original {
+		t.Errorf("expected %+v, got %+v", *original, *clone)
+	}
+
+	clone.Time = 10
+	clone.Memory = 128 * 1024
+	clone.Extra = ""
+	if original.Time != 3 || original.Memory != 64*1024 || original.Extra != "x=42" {
+		t.Errorf("expected original to be unchanged, got %+v", *original)
+	}
+
+	var nilParams *Params
+	if nilParams.Clone() != nil {
+		t.Error("expected Clone of nil to be nil")
+	}
+}
+
+func TestNonDefaultKeyLenRoundTrip(t *testing.T) {
+	for _, keyLen := range []uint32{16, 48, 64, 128} {
+		t.Run(fmt.Sprintf("KeyLen=%d", keyLen), func(t *testing.T) {
+			params := &Params{Time: 1, Memory: 1024, Threads: 1, KeyLen: keyLen}
+			hash, err := GenerateFromPassword([]byte("password"), params)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			extracted, err := ExtractParams(hash)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if extracted.KeyLen != keyLen {
+				t.Errorf("expected inferred KeyLen %d, got %d", keyLen, extracted.KeyLen)
+			}
+
+			if err := CompareHashAndPassword(hash, []byte("password")); err != nil {
+				t.Errorf("expected password to verify, got %v", err)
+			}
+			if err := CompareHashAndPassword(hash, []byte("wrong")); err != ErrMismatchedHashAndPassword {
+				t.Errorf("expected %v, got %v", ErrMismatchedHashAndPassword, err)
+			}
+
+			// The stored digest is IDKey at exactly the inferred length
+			_, salt, digest, err := decodeHash(string(hash), nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if want := argon2.IDKey([]byte("password"), salt, 1, 1024, 1, keyLen); string(want) != string(digest) {
+				t.Error("expected the stored digest to equal IDKey at the inferred KeyLen")
+			}
+		})
+	}
+}
+
+func TestMemorySuffix(t *testing.T) {
+	salt := []byte("0123456789abcdef")
+	digest := argon2.IDKey([]byte("password"), salt, 1, 1024, 1, 32)
+	tail := "$" + base64.RawStdEncoding.EncodeToString(salt) + "$" + base64.RawStdEncoding.EncodeToString(digest)
+
+	for _, m := range []string{"1024", "1024K", "1024KiB", "1MiB"} {
+		hash := "$argon2id$v=19$m=" + m + ",t=1,p=1" + tail
+		if err := CompareHashAndPassword([]byte(hash), []byte("password")); err != nil {
+			t.Errorf("m=%s: expected hash to verify, got %v", m, err)
+		}
+	}
+
+	params, err := ExtractParams([]byte("$argon2id$v=19$m=64MiB,t=1,p=1" + tail))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if params.Memory != 65536 {
+		t.Errorf("expected m=64MiB to normalize to 65536 KB, got %d", params.Memory)
+	}
+
+	// Re-encoding emits a plain integer
+	reencoded, err := GenerateFromPassword([]byte("password"), &Params{Time: 1, Memory: params.Memory, Threads: 1, KeyLen: 32})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(reencoded), "$m=65536,") {
+		t.Errorf("expected plain KB in %s", reencoded)
+	}
+
+	for _, m := range []string{"64GB", "4194304MiB", "MiB", "64mib", "-1K"} {
+		hash := "$argon2id$v=19$m=" + m + ",t=1,p=1" + tail
+		if _, err := ExtractParams([]byte(hash)); err != ErrInvalidHash {
+			t.Errorf("m=%s: expected %v, got %v", m, ErrInvalidHash, err)
+		}
+	}
+}
+
+func TestExtractSalt(t *testing.T) {
+	salt := []byte("0123456789abcdef")
+	params := &Params{Time: 1, Memory: 1024, Threads: 1, KeyLen: 32}
+
+	hash, err := GenerateFromPasswordWithSalt([]byte("password"), salt, params)
+	if err != nil {
+		t.Fatal(err)
+	}
+	extracted, err := ExtractSalt(hash)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(extracted) != string(salt) {
+		t.Errorf("expected salt %q, got %q", salt, extracted)
+	}
+
+	// The same salt and password reproduce the same hash
+	again, err := GenerateFromPasswordWithSalt([]byte("password"), extracted, params)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(again) != string(hash) {
+		t.Errorf("expected %s, got %s", hash, again)
+	}
+	if err := CompareHashAndPassword(hash, []byte("password")); err != nil {
+		t.Errorf("expected hash to verify, got %v", err)
+	}
+
+	if _, err := GenerateFromPasswordWithSalt([]byte("password"), []byte("short"), params); err == nil {
+		t.Error("expected error for a salt of the wrong length")
+	}
+	if _, err := ExtractSalt([]byte("not a hash")); err == nil {
+		t.Error("expected error for a malformed hash")
+	}
+}
+
+func TestGenerateFromPasswordWipe(t *testing.T) {
+	password := []byte("password")
+	hash, err := GenerateFromPasswordWipe(password, &Params{Time: 1, Memory: 1024, Threads: 1, KeyLen: 32})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i, b := range password {
+		if b != 0 {
+			t.Fatalf("expected password to be zeroed, byte %d is %#x", i, b)
+		}
+	}
+	if err := CompareHashAndPassword(hash, []byte("password")); err != nil {
+		t.Errorf("expected hash of the original password, got %v", err)
+	}
+
+	// Wiped on error too
+	password = []byte("password")
+	if _, err := GenerateFromPasswordWipe(password, &Params{Time: 0}); err == nil {
+		t.Fatal("expected error for invalid params")
+	}
+	if string(password) != string(make([]byte, len(password))) {
+		t.Error("expected password to be zeroed after an error")
+	}
+}
+
+func TestVariantCapitalization(t *testing.T) {
+	hash, err := GenerateFromPassword([]byte("password"), &Params{Time: 1, Memory: 1024, Threads: 1, KeyLen: 32})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasPrefix(string(hash), "$argon2id$") {
+		t.Errorf("expected canonical lowercase variant, got %s", hash)
+	}
+
+	for _, variant := range []string{"Argon2id", "ARGON2ID", "aRgOn2Id"} {
+		mixed := strings.Replace(string(hash), "argon2id", variant, 1)
+		if err := CompareHashAndPassword([]byte(mixed), []byte("password")); err != nil {
+			t.Errorf("%s: expected hash to verify, got %v", variant, err)
+		}
+	}
+
+	for _, variant := range []string{"Argon2i", "ARGON2D"} {
+		other := strings.Replace(string(hash), "argon2id", variant, 1)
+		if err := CompareHashAndPassword([]byte(other), []byte("password")); err != ErrIncompatibleVariant {
+			t.Errorf("%s: expected %v, got %v", variant, ErrIncompatibleVariant, err)
+		}
+	}
+}
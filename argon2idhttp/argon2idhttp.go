@@ -0,0 +1,114 @@
+// Package argon2idhttp provides net/http helpers for the argon2id package.
+//
+// It centralizes the boilerplate web handlers repeat around registration and
+// login, keeping HTTP concerns out of the core package:
+//
+//	hasher := argon2id.NewHasher(nil)
+//
+//	func register(w http.ResponseWriter, r *http.Request) {
+//		hash, err := argon2idhttp.HashFormField(r, "password", hasher)
+//		if err != nil {
+//			http.Error(w, "Bad request", http.StatusBadRequest)
+//			return
+//		}
+//		// Store hash...
+//	}
+//
+//	func login(w http.ResponseWriter, r *http.Request) {
+//		err := hasher.CompareHashAndPassword(storedHash, []byte(r.FormValue("password")))
+//		if err != nil {
+//			log.Printf("login: %v", err)
+//			argon2idhttp.WriteAuthError(w, err)
+//			return
+//		}
+//		// Start session...
+//	}
+package argon2idhttp
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/sixcolors/argon2id"
+)
+
+// ErrMissingField is returned when the requested form field is absent or empty.
+var ErrMissingField = errors.New("argon2idhttp: missing form field")
+
+// HashFormField reads a form field from r and hashes it with h.
+//
+// The request form is parsed if needed. ErrMissingField is returned if the
+// field is absent or empty. If h is nil, a Hasher with default parameters
+// is used.
+func HashFormField(r *http.Request, field string, h *argon2id.Hasher) ([]byte, error) {
+	if err := r.ParseForm(); err != nil {
+		return nil, err
+	}
+
+	value := r.Form.Get(field)
+	if value == "" {
+		return nil, ErrMissingField
+	}
+
+	if h == nil {
+		h = argon2id.NewHasher(nil)
+	}
+	return h.GenerateFromPassword([]byte(value))
+}
+
+// WriteUnauthorized writes a 401 response if err is
+// argon2id.ErrMismatchedHashAndPassword and reports whether it did.
+//
+// The response body is a generic message so clients cannot learn why
+// authentication failed. Other errors are left for the caller to handle.
+func WriteUnauthorized(w http.ResponseWriter, err error) bool {
+	if !errors.Is(err, argon2id.ErrMismatchedHashAndPassword) {
+		return false
+	}
+	http.Error(w, "Invalid credentials", http.StatusUnauthorized)
+	return true
+}
+
+// authErrors are the verification errors WriteAuthError answers with 401:
+// a wrong password, a rejected candidate, a hash refused by
+// CompareHashAndPasswordExpect and every way a stored hash can fail to
+// decode.
+var authErrors = []error{
+	argon2id.ErrMismatchedHashAndPassword,
+	argon2id.ErrPasswordTooLong,
+	argon2id.ErrInvalidHash,
+	argon2id.ErrHashTooShort,
+	argon2id.ErrIncompatibleVersion,
+	argon2id.ErrIncompatibleVariant,
+	argon2id.ErrTruncatedDigest,
+	argon2id.ErrHashTooExpensive,
+	argon2id.ErrWeakSalt,
+	argon2id.ErrUnexpectedParams,
+}
+
+// WriteAuthError writes the response for a failed verification.
+//
+// A wrong password and a stored hash that cannot be verified (malformed,
+// truncated, unsupported, too expensive or not matching the expected params)
+// get the same 401 with the same generic body, so clients cannot tell a bad
+// password from a corrupt or policy-mismatched account. A WWW-Authenticate
+// header already set by the caller is kept; otherwise one for a form-based
+// scheme is added, as RFC 9110 requires for 401 responses. Any other error,
+// e.g. a misconfigured PostHash transform, gets a generic 500. Nothing is
+// written if err is nil. Callers should log err themselves, as the response
+// deliberately omits it.
+func WriteAuthError(w http.ResponseWriter, err error) {
+	if err == nil {
+		return
+	}
+	for _, authErr := range authErrors {
+		if errors.Is(err, authErr) {
+			if w.Header().Get("WWW-Authenticate") == "" {
+				w.Header().Set("WWW-Authenticate", `Form realm="login"`)
+			}
+			http.Error(w, "Invalid credentials", http.StatusUnauthorized)
+			return
+		}
+	}
+	http.Error(w, "Internal server error", http.StatusInternalServerError)
+}
@@ -0,0 +1,120 @@
+//go:build !fips && !goexperiment.boringcrypto
+
+package argon2idhttp
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/sixcolors/argon2id"
+)
+
+var testHasher = argon2id.NewHasher(&argon2id.Params{Time: 1, Memory: 1024, Threads: 1, KeyLen: 32})
+
+func newFormRequest(form url.Values) *http.Request {
+	r := httptest.NewRequest(http.MethodPost, "/register", strings.NewReader(form.Encode()))
+	r.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	return r
+}
+
+func TestHashFormField(t *testing.T) {
+	r := newFormRequest(url.Values{"password": {"pa$$word"}})
+
+	hash, err := HashFormField(r, "password", testHasher)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := argon2id.CompareHashAndPassword(hash, []byte("pa$$word")); err != nil {
+		t.Error("expected form value and hash to match")
+	}
+}
+
+func TestHashFormFieldMissing(t *testing.T) {
+	tests := []struct {
+		name string
+		form url.Values
+	}{
+		{"absent", url.Values{"email": {"user@example.com"}}},
+		{"empty", url.Values{"password": {""}}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := HashFormField(newFormRequest(tt.form), "password", testHasher)
+			if err != ErrMissingField {
+				t.Errorf("expected %v, got %v", ErrMissingField, err)
+			}
+		})
+	}
+}
+
+func TestWriteUnauthorized(t *testing.T) {
+	w := httptest.NewRecorder()
+	if !WriteUnauthorized(w, argon2id.ErrMismatchedHashAndPassword) {
+		t.Fatal("expected mismatch to be written")
+	}
+	if w.Code != http.StatusUnauthorized {
+		t.Errorf("expected status %d, got %d", http.StatusUnauthorized, w.Code)
+	}
+
+	for _, err := range []error{nil, errors.New("other")} {
+		w := httptest.NewRecorder()
+		if WriteUnauthorized(w, err) {
+			t.Errorf("expected %v not to be written", err)
+		}
+		if w.Body.Len() != 0 {
+			t.Errorf("expected no response body for %v", err)
+		}
+	}
+}
+
+func TestWriteAuthError(t *testing.T) {
+	hash, err := testHasher.GenerateFromPassword([]byte("password"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	mismatch := testHasher.CompareHashAndPassword(hash, []byte("wrong"))
+	corrupt := testHasher.CompareHashAndPassword([]byte("$argon2id$v=19$garbage"), []byte("password"))
+
+	var bodies []string
+	for _, err := range []error{mismatch, corrupt, argon2id.ErrTruncatedDigest, argon2id.ErrInvalidThreads, argon2id.ErrUnexpectedParams} {
+		w := httptest.NewRecorder()
+		WriteAuthError(w, err)
+		if w.Code != http.StatusUnauthorized {
+			t.Errorf("expected status %d for %v, got %d", http.StatusUnauthorized, err, w.Code)
+		}
+		if w.Header().Get("WWW-Authenticate") == "" {
+			t.Errorf("expected a WWW-Authenticate header for %v", err)
+		}
+		bodies = append(bodies, w.Body.String())
+	}
+	for _, body := range bodies[1:] {
+		if body != bodies[0] {
+			t.Errorf("expected identical bodies, got %q and %q", bodies[0], body)
+		}
+	}
+
+	w := httptest.NewRecorder()
+	w.Header().Set("WWW-Authenticate", `Basic realm="api"`)
+	WriteAuthError(w, mismatch)
+	if got := w.Header().Get("WWW-Authenticate"); got != `Basic realm="api"` {
+		t.Errorf("expected caller's WWW-Authenticate header to be kept, got %q", got)
+	}
+
+	w = httptest.NewRecorder()
+	WriteAuthError(w, errors.New("database unavailable"))
+	if w.Code != http.StatusInternalServerError || strings.Contains(w.Body.String(), "database") {
+		t.Errorf("expected a generic 500, got %d %q", w.Code, w.Body.String())
+	}
+
+	w = httptest.NewRecorder()
+	WriteAuthError(w, nil)
+	if w.Body.Len() != 0 || len(w.Header()) != 0 {
+		t.Error("expected nothing written for a nil error")
+	}
+}
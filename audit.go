@@ -0,0 +1,102 @@
+package argon2id
+
+import (
+	"cmp"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+)
+
+// ParamHistogram counts the distinct parameter sets used across a corpus of
+// stored hashes.
+//
+// It is intended for tracking a gradual rehash rollout, e.g. "80% of users
+// are still on the old parameters". Each hash is parsed with ExtractParams;
+// the first hash that fails to parse aborts the count with an error
+// identifying its index.
+func ParamHistogram(hashes [][]byte) (map[Params]int, error) {
+	histogram := make(map[Params]int)
+	for i, hash := range hashes {
+		params, err := ExtractParams(hash)
+		if err != nil {
+			return nil, fmt.Errorf("argon2id: hash %d: %w", i, err)
+		}
+		histogram[*params]++
+	}
+	return histogram, nil
+}
+
+// LooksMisconfigured reports whether a stored hash was generated with
+// implausibly weak parameters, as produced by a buggy configuration.
+//
+// The most common such bug is setting Memory in MB instead of KB, e.g.
+// Memory: 64 for what was meant to be 64 MB, which yields hashes with less
+// than 1 MB of memory. A Time below 2 is flagged as well, since a single
+// pass usually means Time was left at its zero value and clamped, or
+// confused with Threads. reason explains every finding and suggests the
+// likely fix; it is empty when suspicious is false. Parse errors from
+// ExtractParams are returned as is.
+func LooksMisconfigured(hashedPassword []byte) (suspicious bool, reason string, err error) {
+	params, err := ExtractParams(hashedPassword)
+	if err != nil {
+		return false, "", err
+	}
+
+	var reasons []string
+	if params.Memory < 1024 {
+		reasons = append(reasons, fmt.Sprintf("memory is %d KB, below 1 MB; was it configured in MB instead of KB (e.g. %d * 1024)?", params.Memory, params.Memory))
+	}
+	if params.Time < 2 {
+		reasons = append(reasons, fmt.Sprintf("time is %d, a single pass; check that Time is set (DefaultTime is %d)", params.Time, DefaultTime))
+	}
+	return len(reasons) > 0, strings.Join(reasons, "; "), nil
+}
+
+// CostRow is one distinct parameter set in a CostReport.
+type CostRow struct {
+	Params      Params
+	Count       int           // Number of hashes using Params
+	MemoryBytes uint64        // EstimateMemory of Params
+	Latency     time.Duration // Measured time to verify one hash, 0 if not measured
+}
+
+// CostReport summarizes a corpus of stored hashes by parameter set, sorted
+// weakest first, so a migration can start with the hashes that are
+// cheapest to attack.
+//
+// Rows are ordered by Memory * Time, the work per guess, then by Memory,
+// Threads, KeyLen and Extra, so the order is deterministic.
+// Each distinct parameter set is timed once with MeasureHashTime on the
+// current machine, so the report takes roughly the sum of their latencies
+// to compute. Sets outside the limits GenerateFromPassword enforces, such as
+// below MinMemory or beyond MaxMemory, are not timed and report a Latency of
+// 0. Parse errors are reported as by ParamHistogram.
+func CostReport(hashes [][]byte) ([]CostRow, error) {
+	histogram, err := ParamHistogram(hashes)
+	if err != nil {
+		return nil, err
+	}
+
+	rows := make([]CostRow, 0, len(histogram))
+	for params, count := range histogram {
+		row := CostRow{Params: params, Count: count, MemoryBytes: EstimateMemory(&params)}
+		if withinPolicy(&params) {
+			if row.Latency, err = MeasureHashTime(&params); err != nil {
+				return nil, err
+			}
+		}
+		rows = append(rows, row)
+	}
+
+	slices.SortFunc(rows, func(a, b CostRow) int {
+		return cmp.Or(
+			cmp.Compare(uint64(a.Params.Memory)*uint64(a.Params.Time), uint64(b.Params.Memory)*uint64(b.Params.Time)),
+			cmp.Compare(a.Params.Memory, b.Params.Memory),
+			cmp.Compare(a.Params.Threads, b.Params.Threads),
+			cmp.Compare(a.Params.KeyLen, b.Params.KeyLen),
+			strings.Compare(a.Params.Extra, b.Params.Extra),
+		)
+	})
+	return rows, nil
+}
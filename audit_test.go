@@ -0,0 +1,169 @@
+//go:build !fips && !goexperiment.boringcrypto
+
+package argon2id
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestParamHistogram(t *testing.T) {
+	oldParams := &Params{Time: 1, Memory: 1024, Threads: 1, KeyLen: 32}
+	newParams := &Params{Time: 2, Memory: 2048, Threads: 1, KeyLen: 32}
+
+	var hashes [][]byte
+	for i := 0; i < 4; i++ {
+		params := oldParams
+		if i == 0 {
+			params = newParams
+		}
+		hash, err := GenerateFromPassword([]byte("password"), params)
+		if err != nil {
+			t.Fatal(err)
+		}
+		hashes = append(hashes, hash)
+	}
+
+	histogram, err := ParamHistogram(hashes)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(histogram) != 2 {
+		t.Errorf("expected 2 distinct param sets, got %d", len(histogram))
+	}
+	if histogram[*oldParams] != 3 {
+		t.Errorf("expected 3 hashes on old params, got %d", histogram[*oldParams])
+	}
+	if histogram[*newParams] != 1 {
+		t.Errorf("expected 1 hash on new params, got %d", histogram[*newParams])
+	}
+
+	_, err = ParamHistogram(append(hashes, []byte("corrupt")))
+	if !errors.Is(err, ErrHashTooShort) {
+		t.Errorf("expected %v, got %v", ErrHashTooShort, err)
+	}
+}
+
+func TestLooksMisconfigured(t *testing.T) {
+	tests := []struct {
+		name       string
+		params     *Params
+		suspicious bool
+		mentions   []string
+	}{
+		{"normal", &Params{Time: 2, Memory: 19 * 1024, Threads: 1, KeyLen: 32}, false, nil},
+		{"memory in MB", &Params{Time: 3, Memory: 64, Threads: 2, KeyLen: 32}, true, []string{"64 KB", "MB instead of KB"}},
+		{"single pass", &Params{Time: 1, Memory: 1024, Threads: 1, KeyLen: 32}, true, []string{"time is 1"}},
+		{"both", &Params{Time: 1, Memory: 16, Threads: 1, KeyLen: 32}, true, []string{"16 KB", "time is 1"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			hash, err := GenerateFromPassword([]byte("password"), tt.params)
+			if err != nil {
+				t.Fatal(err)
+			}
+			suspicious, reason, err := LooksMisconfigured(hash)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if suspicious != tt.suspicious {
+				t.Errorf("expected suspicious = %v, got %v (%s)", tt.suspicious, suspicious, reason)
+			}
+			if !suspicious && reason != "" {
+				t.Errorf("expected no reason, got %q", reason)
+			}
+			for _, m := range tt.mentions {
+				if !strings.Contains(reason, m) {
+					t.Errorf("expected reason %q to mention %q", reason, m)
+				}
+			}
+		})
+	}
+
+	if _, _, err := LooksMisconfigured([]byte("not a hash")); err == nil {
+		t.Error("expected error for a malformed hash")
+	}
+}
+
+func TestCostReport(t *testing.T) {
+	corpus := []struct {
+		params *Params
+		count  int
+	}{
+		{&Params{Time: 2, Memory: 2048, Threads: 1, KeyLen: 32}, 1},
+		{&Params{Time: 1, Memory: 1024, Threads: 1, KeyLen: 32}, 3},
+		{&Params{Time: 3, Memory: 1024, Threads: 1, KeyLen: 32}, 2},
+	}
+
+	var hashes [][]byte
+	for _, c := range corpus {
+		for i := 0; i < c.count; i++ {
+			hash, err := GenerateFromPassword([]byte("password"), c.params)
+			if err != nil {
+				t.Fatal(err)
+			}
+			hashes = append(hashes, hash)
+		}
+	}
+
+	rows, err := CostReport(hashes)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(rows) != 3 {
+		t.Fatalf("expected 3 rows, got %d", len(rows))
+	}
+
+	// Weakest first: 1024*1, 1024*3, 2048*2
+	want := []struct {
+		time  uint32
+		count int
+	}{{1, 3}, {3, 2}, {2, 1}}
+	for i, w := range want {
+		row := rows[i]
+		if row.Params.Time != w.time || row.Count != w.count {
+			t.Errorf("row %d: expected Time %d x%d, got Time %d x%d", i, w.time, w.count, row.Params.Time, row.Count)
+		}
+		if row.Latency <= 0 {
+			t.Errorf("row %d: expected a measured latency", i)
+		}
+		if row.MemoryBytes != EstimateMemory(&row.Params) {
+			t.Errorf("row %d: expected MemoryBytes %d, got %d", i, EstimateMemory(&row.Params), row.MemoryBytes)
+		}
+	}
+
+	if _, err := CostReport([][]byte{[]byte("not a hash")}); err == nil {
+		t.Error("expected error for a malformed hash")
+	}
+}
+
+func TestCostReportOutOfPolicy(t *testing.T) {
+	// Decodable, but below MinMemory: reported without a latency
+	weak := []byte("$argon2id$v=19$m=4,t=1,p=1$c29tZXNhbHRzb21lc2FsdA$Jl9aMRiFdmA52uqiivDqYVot6fVz3erNUlWPoxyg46A")
+	hashes := [][]byte{weak}
+	for _, keyLen := range []uint32{32, 16} {
+		hash, err := GenerateFromPassword([]byte("password"), &Params{Time: 1, Memory: 1024, Threads: 1, KeyLen: keyLen})
+		if err != nil {
+			t.Fatal(err)
+		}
+		hashes = append(hashes, hash)
+	}
+
+	rows, err := CostReport(hashes)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(rows) != 3 {
+		t.Fatalf("expected 3 rows, got %d", len(rows))
+	}
+	if rows[0].Params.Memory != 4 || rows[0].Latency != 0 {
+		t.Errorf("expected the m=4 set first without a latency, got %+v", rows[0])
+	}
+	// Sets that differ only in KeyLen are ordered by it
+	if rows[1].Params.KeyLen != 16 || rows[2].Params.KeyLen != 32 {
+		t.Errorf("expected KeyLen 16 before 32, got %d and %d", rows[1].Params.KeyLen, rows[2].Params.KeyLen)
+	}
+}
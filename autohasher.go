@@ -0,0 +1,84 @@
+package argon2id
+
+import (
+	"sync"
+	"time"
+)
+
+// DefaultAutoHasherTarget is the verification latency AutoHasher calibrates
+// for unless changed with SetAutoHasherTarget.
+const DefaultAutoHasherTarget = 250 * time.Millisecond
+
+// autoHasher is the package's lazily calibrated Hasher. Tests replace it.
+var autoHasher = newLazyHasher(DefaultAutoHasherTarget)
+
+// lazyHasher calibrates a Hasher on first use.
+type lazyHasher struct {
+	hasher *Hasher
+	once   sync.Once
+
+	// mu guards target and calibrated, so that SetAutoHasherTarget either
+	// changes the target before calibration reads it or reports failure
+	mu         sync.Mutex
+	target     time.Duration
+	calibrated bool
+}
+
+func newLazyHasher(target time.Duration) *lazyHasher {
+	return &lazyHasher{target: target}
+}
+
+// get returns the Hasher, calibrating it exactly once.
+func (l *lazyHasher) get() *Hasher {
+	l.once.Do(func() {
+		l.mu.Lock()
+		l.calibrated = true
+		target := l.target
+		l.mu.Unlock()
+
+		params, err := Calibrate(target, DefaultMemory)
+		if err != nil {
+			params = DefaultParams()
+		}
+		l.hasher = NewHasher(params)
+	})
+	return l.hasher
+}
+
+// setTarget sets the calibration target unless calibration has started.
+func (l *lazyHasher) setTarget(target time.Duration) bool {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.calibrated {
+		return false
+	}
+	l.target = target
+	return true
+}
+
+// AutoHasher returns a Hasher tuned to the current machine.
+//
+// On first use it runs Calibrate with DefaultMemory, picking the highest
+// Time that keeps a verification within DefaultAutoHasherTarget (or the
+// target set with SetAutoHasherTarget). This blocks the first caller for a
+// few multiples of the target; concurrent callers wait for the same
+// calibration, which happens exactly once per process. If even a single
+// iteration exceeds the target, DefaultParams() are used.
+//
+// The calibrated params can be lower than DefaultParams() on slow hardware
+// and differ between machines; hashes remain verifiable everywhere since
+// the params are stored in each hash. Applications that need a fixed,
+// auditable policy should use NewHasher with explicit params instead.
+func AutoHasher() *Hasher {
+	return autoHasher.get()
+}
+
+// SetAutoHasherTarget sets the latency AutoHasher calibrates for. It only
+// has an effect before AutoHasher is first called and reports whether it
+// did.
+func SetAutoHasherTarget(target time.Duration) bool {
+	if target <= 0 {
+		return false
+	}
+	return autoHasher.setTarget(target)
+}
@@ -0,0 +1,92 @@
+//go:build !fips && !goexperiment.boringcrypto
+
+package argon2id
+
+import (
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// useFreshAutoHasher replaces the package AutoHasher for the test.
+func useFreshAutoHasher(t *testing.T) {
+	t.Helper()
+	saved := autoHasher
+	autoHasher = newLazyHasher(DefaultAutoHasherTarget)
+	t.Cleanup(func() { autoHasher = saved })
+}
+
+func TestAutoHasherCalibratesOnce(t *testing.T) {
+	useFreshAutoHasher(t)
+
+	// Every sample takes 1s on the fake clock, well over the target, so
+	// calibration stops after a single sample
+	var readings atomic.Int64
+	now := time.Unix(0, 0)
+	var mu sync.Mutex
+	SetTimeSource(func() time.Time {
+		readings.Add(1)
+		mu.Lock()
+		defer mu.Unlock()
+		now = now.Add(time.Second)
+		return now
+	})
+	t.Cleanup(func() { SetTimeSource(nil) })
+
+	var wg sync.WaitGroup
+	hashers := make([]*Hasher, 8)
+	for i := range hashers {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			hashers[i] = AutoHasher()
+		}()
+	}
+	wg.Wait()
+
+	for _, h := range hashers[1:] {
+		if h != hashers[0] {
+			t.Fatal("expected every caller to get the same Hasher")
+		}
+	}
+	if got := readings.Load(); got != 2 {
+		t.Errorf("expected one calibration sample (2 clock readings), got %d readings", got)
+	}
+	if *hashers[0].Params != *DefaultParams() {
+		t.Errorf("expected DefaultParams when the target is unreachable, got %+v", hashers[0].Params)
+	}
+}
+
+func TestSetAutoHasherTarget(t *testing.T) {
+	useFreshAutoHasher(t)
+
+	// The nth sample takes n*100ms on the fake clock: Time 1 and 2 fit a
+	// 250ms target, Time 4 and then 3 do not
+	now := time.Unix(0, 0)
+	readings := 0
+	SetTimeSource(func() time.Time {
+		readings++
+		if readings%2 == 0 {
+			now = now.Add(time.Duration(readings/2) * 100 * time.Millisecond)
+		}
+		return now
+	})
+	t.Cleanup(func() { SetTimeSource(nil) })
+
+	if SetAutoHasherTarget(0) {
+		t.Error("expected a non-positive target to be rejected")
+	}
+	if !SetAutoHasherTarget(250 * time.Millisecond) {
+		t.Fatal("expected the target to be accepted before first use")
+	}
+
+	h := AutoHasher()
+	if h.Params.Time != 2 || h.Params.Memory != DefaultMemory {
+		t.Errorf("expected Time 2 at DefaultMemory, got %+v", h.Params)
+	}
+
+	if SetAutoHasherTarget(time.Second) {
+		t.Error("expected the target to be fixed after first use")
+	}
+}
@@ -0,0 +1,91 @@
+// Package bcryptcompat mirrors the golang.org/x/crypto/bcrypt function
+// signatures on top of argon2id.
+//
+// It exists to make migrations mechanical: a codebase can swap its import of
+// golang.org/x/crypto/bcrypt for this package and keep compiling, then move
+// to the argon2id package's native API at its own pace.
+//
+//	// Before
+//	hash, err := bcrypt.GenerateFromPassword(password, bcrypt.DefaultCost)
+//
+//	// After
+//	hash, err := bcryptcompat.GenerateFromPassword(password, bcryptcompat.DefaultCost)
+//
+// Hashes produced here are regular argon2id hashes; they are not readable by
+// bcrypt, and bcrypt hashes are not readable here.
+//
+// # Cost mapping
+//
+// Each bcrypt cost step doubles the work. The mapping preserves that by
+// doubling Argon2 memory from cost 4 up to MaxMemory, then doubling Time up
+// to MaxTime. DefaultCost maps to argon2id.DefaultParams():
+//
+//	cost    memory    time
+//	4       1 MB      3
+//	5       2 MB      3
+//	6       4 MB      3
+//	7       8 MB      3
+//	8       16 MB     3
+//	9       32 MB     3
+//	10      64 MB     3   (DefaultCost, argon2id defaults)
+//	11      128 MB    3
+//	12      256 MB    3
+//	13      512 MB    3
+//	14      1 GB      3
+//	15      1 GB      6
+//	16      1 GB      12
+//	17      1 GB      24
+//	18      1 GB      48
+//	19      1 GB      96
+//	20-31   1 GB      100 (MaxTime)
+//
+// Threads and KeyLen always use the argon2id defaults.
+package bcryptcompat
+
+import (
+	"fmt"
+
+	"github.com/sixcolors/argon2id"
+)
+
+// Cost bounds, matching golang.org/x/crypto/bcrypt.
+const (
+	MinCost     = 4  // the minimum allowable cost as passed in to GenerateFromPassword
+	MaxCost     = 31 // the maximum allowable cost as passed in to GenerateFromPassword
+	DefaultCost = 10 // the cost that will actually be set if a cost below MinCost is passed into GenerateFromPassword
+)
+
+// ErrMismatchedHashAndPassword is returned from CompareHashAndPassword when a
+// password and hash do not match.
+var ErrMismatchedHashAndPassword = argon2id.ErrMismatchedHashAndPassword
+
+// InvalidCostError is returned when the cost passed to GenerateFromPassword
+// is above MaxCost.
+type InvalidCostError int
+
+func (ic InvalidCostError) Error() string {
+	return fmt.Sprintf("bcryptcompat: cost %d is outside allowed inclusive range %d..%d", int(ic), MinCost, MaxCost)
+}
+
+// GenerateFromPassword returns the argon2id hash of the password at the
+// parameters mapped from the given bcrypt cost.
+//
+// If the cost given is less than MinCost, the cost will be set to
+// DefaultCost, as bcrypt does. An InvalidCostError is returned for costs
+// above MaxCost.
+func GenerateFromPassword(password []byte, cost int) ([]byte, error) {
+	if cost < MinCost {
+		cost = DefaultCost
+	}
+	if cost > MaxCost {
+		return nil, InvalidCostError(cost)
+	}
+	return argon2id.GenerateFromPassword(password, argon2id.ParamsFromBcryptCost(cost))
+}
+
+// CompareHashAndPassword compares an argon2id hashed password with its
+// possible plaintext equivalent. Returns nil on success, or an error on
+// failure.
+func CompareHashAndPassword(hashedPassword, password []byte) error {
+	return argon2id.CompareHashAndPassword(hashedPassword, password)
+}
@@ -0,0 +1,78 @@
+//go:build !fips && !goexperiment.boringcrypto
+
+package bcryptcompat
+
+import (
+	"testing"
+
+	"github.com/sixcolors/argon2id"
+)
+
+func TestCostTable(t *testing.T) {
+	tests := []struct {
+		cost   int
+		memory uint32
+		time   uint32
+	}{
+		{4, 1024, 3},
+		{9, 32 * 1024, 3},
+		{DefaultCost, argon2id.DefaultMemory, argon2id.DefaultTime},
+		{14, argon2id.MaxMemory, 3},
+		{15, argon2id.MaxMemory, 6},
+		{19, argon2id.MaxMemory, 96},
+		{20, argon2id.MaxMemory, argon2id.MaxTime},
+		{MaxCost, argon2id.MaxMemory, argon2id.MaxTime},
+	}
+
+	for _, tt := range tests {
+		params := argon2id.ParamsFromBcryptCost(tt.cost)
+		if params.Memory != tt.memory || params.Time != tt.time {
+			t.Errorf("cost %d: expected m=%d,t=%d, got m=%d,t=%d", tt.cost, tt.memory, tt.time, params.Memory, params.Time)
+		}
+	}
+}
+
+func TestCostTableMonotonic(t *testing.T) {
+	prev := argon2id.ParamsFromBcryptCost(MinCost)
+	for cost := MinCost + 1; cost <= MaxCost; cost++ {
+		params := argon2id.ParamsFromBcryptCost(cost)
+		if params.Memory < prev.Memory || params.Time < prev.Time {
+			t.Errorf("cost %d params %+v weaker than cost %d params %+v", cost, params, cost-1, prev)
+		}
+		prev = params
+	}
+}
+
+func TestGenerateAndCompare(t *testing.T) {
+	hash, err := GenerateFromPassword([]byte("password"), MinCost)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := CompareHashAndPassword(hash, []byte("password")); err != nil {
+		t.Error("expected password and hash to match")
+	}
+	if err := CompareHashAndPassword(hash, []byte("wrong")); err != ErrMismatchedHashAndPassword {
+		t.Errorf("expected %v, got %v", ErrMismatchedHashAndPassword, err)
+	}
+}
+
+func TestGenerateFromPasswordCost(t *testing.T) {
+	// Costs below MinCost fall back to DefaultCost, as in bcrypt
+	hash, err := GenerateFromPassword([]byte("password"), 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	params, err := argon2id.ExtractParams(hash)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if params.Memory != argon2id.DefaultMemory || params.Time != argon2id.DefaultTime {
+		t.Errorf("expected default params, got %+v", params)
+	}
+
+	_, err = GenerateFromPassword([]byte("password"), MaxCost+1)
+	if _, ok := err.(InvalidCostError); !ok {
+		t.Errorf("expected InvalidCostError, got %v", err)
+	}
+}
@@ -0,0 +1,30 @@
+package argon2id
+
+import "math"
+
+// AllValidBoundaryParams returns the corner cases of the valid parameter
+// space: each limit at its minimum and maximum, alone and combined.
+//
+// Every returned set passes GenerateFromPassword's validation, so the slice
+// can drive table-driven round-trip tests in this package and in downstream
+// implementations that need to interoperate with it. Features that widen the
+// accepted parameter space (new variants, versions or limits) should extend
+// this list.
+//
+// A new slice is returned on each call, so callers may modify it freely.
+// Note that the combined maximum set allocates MaxMemory and is slow to hash.
+func AllValidBoundaryParams() []*Params {
+	return []*Params{
+		// All minimums
+		{Time: MinTime, Memory: MinMemory, Threads: MinThreads, KeyLen: MinKeyLen},
+		// Single limit at its maximum
+		{Time: MaxTime, Memory: MinMemory, Threads: MinThreads, KeyLen: DefaultKeyLen},
+		{Time: MinTime, Memory: MaxMemory, Threads: MinThreads, KeyLen: DefaultKeyLen},
+		{Time: MinTime, Memory: MinMemory, Threads: math.MaxUint8, KeyLen: DefaultKeyLen},
+		{Time: MinTime, Memory: MinMemory, Threads: MinThreads, KeyLen: MaxKeyLen},
+		// Defaults
+		DefaultParams(),
+		// All maximums
+		{Time: MaxTime, Memory: MaxMemory, Threads: math.MaxUint8, KeyLen: MaxKeyLen},
+	}
+}
@@ -0,0 +1,52 @@
+//go:build !fips && !goexperiment.boringcrypto
+
+package argon2id
+
+import (
+	"fmt"
+	"testing"
+)
+
+func TestAllValidBoundaryParams(t *testing.T) {
+	for _, params := range AllValidBoundaryParams() {
+		name := fmt.Sprintf("m=%d,t=%d,p=%d,l=%d", params.Memory, params.Time, params.Threads, params.KeyLen)
+		t.Run(name, func(t *testing.T) {
+			skipExpensive(t, params)
+
+			hash, err := GenerateFromPassword([]byte("test"), params)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			if err := CompareHashAndPassword(hash, []byte("test")); err != nil {
+				t.Errorf("password should match its hash: %v", err)
+			}
+
+			extracted, err := ExtractParams(hash)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if *extracted != *params {
+				t.Errorf("params mismatch: expected %+v, got %+v", params, extracted)
+			}
+		})
+	}
+}
+
+func TestAllValidBoundaryParamsIndependent(t *testing.T) {
+	first := AllValidBoundaryParams()
+	first[0].Time = 42
+
+	if AllValidBoundaryParams()[0].Time == 42 {
+		t.Error("expected a fresh slice on each call")
+	}
+}
+
+// skipExpensive skips a test of params that allocate MaxMemory, which takes
+// minutes (longer under -race), unless the -stress flag is set.
+func skipExpensive(t *testing.T, params *Params) {
+	t.Helper()
+	if params.Memory >= MaxMemory && !*stress {
+		t.Skip("allocates MaxMemory; run with -stress")
+	}
+}
@@ -0,0 +1,315 @@
+package argon2id
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"time"
+)
+
+// MeasureHashTime reports how long a single Argon2ID computation with the
+// given params takes on the current machine.
+//
+// If params is nil, DefaultParams() will be used. The params are validated
+// against the same limits as GenerateFromPassword. The measurement covers
+// only the key derivation, not salt generation, encoding or time spent
+// queued behind SetMaxConcurrency. In FIPS builds it returns
+// ErrNotFIPSApproved, and so do the features built on it (Calibrate,
+// RecommendParams, PlanHash); EstimateMigrationTime returns 0.
+func MeasureHashTime(params *Params) (time.Duration, error) {
+	if params == nil {
+		params = DefaultParams()
+	}
+	if fipsRestricted {
+		return 0, ErrNotFIPSApproved
+	}
+	if err := validateParams(params); err != nil {
+		return 0, err
+	}
+
+	salt := make([]byte, SaltLen)
+	if _, err := io.ReadFull(randReader, salt); err != nil {
+		return 0, err
+	}
+
+	_, elapsed := idKeyTimed([]byte("measure"), salt, params)
+	return elapsed, nil
+}
+
+// WarmUp runs rounds throwaway hashes with params so that the first real
+// hash after startup does not pay for faulting in fresh memory pages, e.g.
+// from a readiness probe before an autoscaled instance receives traffic.
+// The hashes go through the same concurrency limit and hashing pool as
+// real ones (see SetMaxConcurrency and SetHashingPool), which they also
+// warm up.
+//
+// If params is nil, DefaultParams() will be used. The params are validated
+// against the same limits as GenerateFromPassword, and rounds must be at
+// least 1. One round per distinct Memory setting in use is usually enough;
+// more help when several hashes run concurrently from the start.
+func WarmUp(params *Params, rounds int) error {
+	if params == nil {
+		params = DefaultParams()
+	}
+	if fipsRestricted {
+		return ErrNotFIPSApproved
+	}
+	if err := validateParams(params); err != nil {
+		return err
+	}
+	if rounds < 1 {
+		return fmt.Errorf("argon2id: warm-up rounds (%d) must be at least 1", rounds)
+	}
+
+	salt := make([]byte, SaltLen)
+	if _, err := io.ReadFull(randReader, salt); err != nil {
+		return err
+	}
+	for range rounds {
+		idKey([]byte("warm-up"), salt, params)
+	}
+	return nil
+}
+
+// RecommendParams picks parameters that fit within a latency and memory budget.
+//
+// It follows the standard Argon2 tuning advice: memory-hardness matters more
+// than iterations, so the largest memory size between minMemoryKiB and
+// maxMemoryKiB that completes a single pass within maxLatency is chosen
+// first. Time is then raised to fill the remaining latency budget.
+//
+// The returned params use DefaultThreads and DefaultKeyLen, and the returned
+// duration is the measured latency of the chosen params. An error is returned
+// if even minMemoryKiB with a single iteration exceeds maxLatency.
+//
+// Measurements are taken on the current machine, so the result should be
+// computed on (or on hardware equivalent to) the production hosts.
+func RecommendParams(maxLatency time.Duration, maxMemoryKiB, minMemoryKiB uint32) (*Params, time.Duration, error) {
+	if maxLatency <= 0 {
+		return nil, 0, fmt.Errorf("argon2id: latency budget (%s) must be positive", maxLatency)
+	}
+	if minMemoryKiB < MinMemory {
+		minMemoryKiB = MinMemory
+	}
+	if maxMemoryKiB > MaxMemory {
+		maxMemoryKiB = MaxMemory
+	}
+	if minMemoryKiB > maxMemoryKiB {
+		return nil, 0, fmt.Errorf("argon2id: minimum memory (%d KB) exceeds maximum memory (%d KB)", minMemoryKiB, maxMemoryKiB)
+	}
+
+	return splitBudget(maxLatency, minMemoryKiB, maxMemoryKiB, MeasureHashTime)
+}
+
+// SplitBudgetMaxMemory is the most memory, in KiB, SplitBudget recommends.
+// Lower it to cap the per-hash memory of its recommendations.
+var SplitBudgetMaxMemory uint32 = MaxMemory
+
+// SplitBudget splits a latency budget between memory and iterations, the
+// decision RecommendParams makes, so it can be inspected or overridden.
+//
+// Memory is maximized first: the largest size between minMemoryKiB and
+// SplitBudgetMaxMemory whose single pass fits in total is chosen, and only
+// then are iterations added to use the rest of the budget. A larger budget
+// therefore buys more memory until the ceiling is reached, and iterations
+// after that. If even minMemoryKiB does not fit, MinTime and minMemoryKiB
+// are returned. Like RecommendParams, it measures on the current machine.
+func SplitBudget(total time.Duration, minMemoryKiB uint32) (timeIterations, memoryKiB uint32) {
+	minMemoryKiB = max(minMemoryKiB, MinMemory)
+	params, _, err := splitBudget(total, minMemoryKiB, max(min(SplitBudgetMaxMemory, MaxMemory), minMemoryKiB), MeasureHashTime)
+	if err != nil {
+		return MinTime, minMemoryKiB
+	}
+	return params.Time, params.Memory
+}
+
+// splitBudget implements RecommendParams and SplitBudget for a valid memory
+// range, timing candidates with measure.
+func splitBudget(total time.Duration, minMemoryKiB, maxMemoryKiB uint32, measure func(*Params) (time.Duration, error)) (*Params, time.Duration, error) {
+	params := &Params{
+		Time:    MinTime,
+		Memory:  maxMemoryKiB,
+		Threads: DefaultThreads,
+		KeyLen:  DefaultKeyLen,
+	}
+
+	elapsed, err := fitMemory(params, total, minMemoryKiB, measure)
+	if err != nil {
+		return nil, 0, err
+	}
+	if elapsed, err = fitTime(params, total, elapsed, measure); err != nil {
+		return nil, 0, err
+	}
+	return params, elapsed, nil
+}
+
+// fitMemory halves params.Memory until a single pass fits the budget and
+// returns the latency of that pass.
+func fitMemory(params *Params, total time.Duration, minMemoryKiB uint32, measure func(*Params) (time.Duration, error)) (time.Duration, error) {
+	elapsed, err := measure(params)
+	if err != nil {
+		return 0, err
+	}
+	for elapsed > total {
+		if params.Memory == minMemoryKiB {
+			return 0, fmt.Errorf("argon2id: latency budget (%s) too small for %d KB of memory (took %s)", total, minMemoryKiB, elapsed)
+		}
+		params.Memory = max(params.Memory/2, minMemoryKiB)
+		if elapsed, err = measure(params); err != nil {
+			return 0, err
+		}
+	}
+	return elapsed, nil
+}
+
+// fitTime raises params.Time to fill the budget left after a single pass
+// took elapsed, and returns the latency of the result. Each pass costs
+// roughly the same as the first.
+func fitTime(params *Params, total, elapsed time.Duration, measure func(*Params) (time.Duration, error)) (time.Duration, error) {
+	passes := total / max(elapsed, 1)
+	if passes <= MinTime {
+		return elapsed, nil
+	}
+
+	params.Time = uint32(min(passes, MaxTime)) // #nosec G115 - bounded by MaxTime
+	elapsed, err := measure(params)
+	if err != nil {
+		return 0, err
+	}
+	for elapsed > total && params.Time > MinTime {
+		scaled := uint32(int64(params.Time) * int64(total) / int64(elapsed)) // #nosec G115 - smaller than params.Time
+		params.Time = max(min(scaled, params.Time-1), MinTime)
+		if elapsed, err = measure(params); err != nil {
+			return 0, err
+		}
+	}
+	return elapsed, nil
+}
+
+// Calibrate is CalibrateContext without cancellation.
+func Calibrate(target time.Duration, memoryHint uint32) (*Params, error) {
+	return CalibrateContext(context.Background(), target, memoryHint)
+}
+
+// CalibrateContext finds the highest Time whose measured latency on the
+// current machine stays within target, keeping Memory fixed.
+//
+// memoryHint is the Memory to calibrate with, in KB (DefaultMemory if 0);
+// the returned params use DefaultThreads and DefaultKeyLen. Time is doubled
+// until target is exceeded and then narrowed by bisection, so calibration
+// takes a few samples of up to about twice target each. An error is
+// returned if a single iteration already exceeds target.
+//
+// ctx is checked before each sample. If it is cancelled, the best params
+// found so far (nil if none fit yet) are returned along with ctx.Err(), so
+// startup code with a deadline can still use a partial result.
+func CalibrateContext(ctx context.Context, target time.Duration, memoryHint uint32) (*Params, error) {
+	if target <= 0 {
+		return nil, fmt.Errorf("argon2id: calibration target (%s) must be positive", target)
+	}
+	if memoryHint == 0 {
+		memoryHint = DefaultMemory
+	}
+	params := &Params{Time: MinTime, Memory: memoryHint, Threads: DefaultThreads, KeyLen: DefaultKeyLen}
+	if err := validateParams(params); err != nil {
+		return nil, err
+	}
+
+	var best *Params
+	var fits, exceeds uint32 // Highest fitting and lowest exceeding Time, 0 if unknown
+	for {
+		if err := ctx.Err(); err != nil {
+			return best, err
+		}
+		elapsed, err := MeasureHashTime(params)
+		if err != nil {
+			return best, err
+		}
+		if elapsed <= target {
+			fits, best = params.Time, params.Clone()
+		} else {
+			exceeds = params.Time
+		}
+
+		next, done := nextCalibrationTime(fits, exceeds)
+		if done {
+			break
+		}
+		params.Time = next
+	}
+
+	if best == nil {
+		return nil, fmt.Errorf("argon2id: calibration target (%s) too small for %d KB of memory", target, memoryHint)
+	}
+	return best, nil
+}
+
+// nextCalibrationTime picks the next Time to sample given the highest Time
+// known to fit and the lowest known to exceed the target (0 if unknown).
+func nextCalibrationTime(fits, exceeds uint32) (next uint32, done bool) {
+	if exceeds == 0 {
+		if fits == MaxTime {
+			return 0, true
+		}
+		return min(2*fits, MaxTime), false
+	}
+	if exceeds-fits <= 1 {
+		return 0, true
+	}
+	return (fits + exceeds) / 2, false
+}
+
+// EstimateMemory returns the number of bytes a single Argon2ID computation
+// with params allocates.
+//
+// Argon2 works on 1 KB blocks and rounds Memory down to a multiple of
+// 4 * Threads blocks, with a floor of 8 * Threads blocks; the estimate
+// applies the same rounding. If params is nil, DefaultParams() will be used.
+// Multiply by the number of concurrent hashes (see SetMaxConcurrency) to
+// size an instance.
+func EstimateMemory(params *Params) uint64 {
+	if params == nil {
+		params = DefaultParams()
+	}
+
+	lanes := 4 * uint64(params.Threads)
+	if lanes == 0 {
+		return 0
+	}
+	blocks := uint64(params.Memory) / lanes * lanes
+	blocks = max(blocks, 2*lanes)
+	return blocks * 1024
+}
+
+// PlanHash previews the hash GenerateFromPassword would produce for params
+// without producing one, e.g. for an admin UI that configures cost.
+//
+// It applies the same defaulting as GenerateFromPassword, clamps Time,
+// Memory, Threads and KeyLen to the limits it enforces (MinTime..MaxTime,
+// MinMemory..MaxMemory, MinThreads.. and MinKeyLen..MaxKeyLen) and
+// validates the rest, so the effective params it returns (a copy) are
+// always accepted by GenerateFromPassword and show what an out-of-range
+// setting would have to become. It also returns the encoded length for
+// sizing the database column (see EncodedLength), the memory a single hash
+// allocates (see EstimateMemory) and the latency on the current machine.
+// The latency is measured with MeasureHashTime, so PlanHash takes as long
+// as one hash.
+func PlanHash(params *Params) (effectiveParams *Params, encodedLen int, estMemory uint64, estLatency time.Duration, err error) {
+	if params == nil {
+		params = DefaultParams()
+	}
+	effective := params.Clone()
+	effective.Time = min(max(effective.Time, MinTime), MaxTime)
+	effective.Memory = min(max(effective.Memory, MinMemory), MaxMemory)
+	effective.Threads = max(effective.Threads, MinThreads)
+	effective.KeyLen = min(max(effective.KeyLen, MinKeyLen), MaxKeyLen)
+	if err := validateParams(effective); err != nil {
+		return nil, 0, 0, 0, err
+	}
+
+	estLatency, err = MeasureHashTime(effective)
+	if err != nil {
+		return nil, 0, 0, 0, err
+	}
+	return effective, EncodedLength(effective), EstimateMemory(effective), estLatency, nil
+}
@@ -0,0 +1,303 @@
+//go:build !fips && !goexperiment.boringcrypto
+
+package argon2id
+
+import (
+	"context"
+	"math"
+	"runtime/debug"
+	"testing"
+	"time"
+)
+
+func TestMeasureHashTime(t *testing.T) {
+	elapsed, err := MeasureHashTime(&Params{Time: 1, Memory: 1024, Threads: 1, KeyLen: 32})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if elapsed <= 0 {
+		t.Errorf("expected positive duration, got %s", elapsed)
+	}
+
+	if _, err := MeasureHashTime(&Params{Time: 0, Memory: 1024, Threads: 1, KeyLen: 32}); err == nil {
+		t.Error("expected error for invalid params")
+	}
+}
+
+// TestDefaultParamsLatency guards the security posture of DefaultParams
+// rather than its behavior: hashing with the defaults must stay within a
+// window that is slow enough to resist offline guessing and fast enough not
+// to turn every login into a DoS vector. A dependency change that silently
+// made the defaults absurdly cheap (e.g. an Argon2 implementation ignoring
+// Memory) or expensive would otherwise go unnoticed.
+//
+// The window of 5ms-500ms is deliberately generous around the typical
+// 50-250ms: a tenth of the low end, twice the high end. The fastest of
+// several samples is used so a busy CI runner cannot fail it by being slow
+// once. It is skipped in -short mode.
+func TestDefaultParamsLatency(t *testing.T) {
+	if testing.Short() {
+		t.Skip("latency guard skipped in short mode")
+	}
+	const (
+		floor   = 5 * time.Millisecond
+		ceiling = 500 * time.Millisecond
+	)
+
+	fastest := time.Duration(math.MaxInt64)
+	for range 3 {
+		elapsed, err := MeasureHashTime(DefaultParams())
+		if err != nil {
+			t.Fatal(err)
+		}
+		fastest = min(fastest, elapsed)
+	}
+
+	if fastest < floor {
+		t.Errorf("DefaultParams() hashed in %s, below %s: the defaults have become too cheap to resist guessing", fastest, floor)
+	}
+	if fastest > ceiling {
+		t.Errorf("DefaultParams() took %s at best, above %s: the defaults have become too expensive to serve logins", fastest, ceiling)
+	}
+}
+
+func TestWarmUp(t *testing.T) {
+	params := &Params{Time: 1, Memory: 1024, Threads: 1, KeyLen: 32}
+	if err := WarmUp(params, 2); err != nil {
+		t.Errorf("expected warm-up to succeed, got %v", err)
+	}
+	if err := WarmUp(params, 0); err == nil {
+		t.Error("expected error for zero rounds")
+	}
+	if err := WarmUp(&Params{}, 1); err == nil {
+		t.Error("expected error for invalid params")
+	}
+}
+
+// BenchmarkWarmUp compares the latency of the first hash after the heap has
+// been returned to the OS (as on a fresh instance) with and without a
+// WarmUp round first:
+//
+//	go test -run '^$' -bench BenchmarkWarmUp -benchtime 20x
+func BenchmarkWarmUp(b *testing.B) {
+	params := &Params{Time: 1, Memory: 64 * 1024, Threads: 1, KeyLen: 32}
+	salt := make([]byte, SaltLen)
+
+	for _, warm := range []bool{false, true} {
+		name := "cold"
+		if warm {
+			name = "warm"
+		}
+		b.Run(name, func(b *testing.B) {
+			for range b.N {
+				b.StopTimer()
+				debug.FreeOSMemory()
+				if warm {
+					if err := WarmUp(params, 1); err != nil {
+						b.Fatal(err)
+					}
+				}
+				b.StartTimer()
+				idKey([]byte("password"), salt, params)
+			}
+		})
+	}
+}
+
+func TestRecommendParams(t *testing.T) {
+	budget := 100 * time.Millisecond
+
+	params, elapsed, err := RecommendParams(budget, 16*1024, 1024)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := validateParams(params); err != nil {
+		t.Fatalf("recommended params do not validate: %v", err)
+	}
+	if params.Memory < 1024 || params.Memory > 16*1024 {
+		t.Errorf("memory %d KB outside requested range", params.Memory)
+	}
+	// Generous margin for noisy CI hardware
+	if elapsed > 3*budget {
+		t.Errorf("measured latency %s far exceeds budget %s", elapsed, budget)
+	}
+
+	if _, err := GenerateFromPassword([]byte("test"), params); err != nil {
+		t.Errorf("recommended params rejected by GenerateFromPassword: %v", err)
+	}
+}
+
+func TestRecommendParamsInvalidBudget(t *testing.T) {
+	if _, _, err := RecommendParams(0, 16*1024, 1024); err == nil {
+		t.Error("expected error for zero latency budget")
+	}
+	if _, _, err := RecommendParams(time.Second, 1024, 16*1024); err == nil {
+		t.Error("expected error when minimum memory exceeds maximum")
+	}
+	if _, _, err := RecommendParams(time.Nanosecond, 1024, 1024); err == nil {
+		t.Error("expected error for unreachable latency budget")
+	}
+}
+
+func TestSplitBudgetPrefersMemory(t *testing.T) {
+	// Deterministic cost model: 1ms per MiB per pass
+	measure := func(params *Params) (time.Duration, error) {
+		return time.Duration(params.Time) * time.Duration(params.Memory/1024) * time.Millisecond, nil
+	}
+	const ceiling = 64 * 1024
+
+	tests := []struct {
+		budget         time.Duration
+		time, memoryKB uint32
+	}{
+		{4 * time.Millisecond, 1, 4 * 1024},
+		{16 * time.Millisecond, 1, 16 * 1024},
+		{64 * time.Millisecond, 1, 64 * 1024},
+		{256 * time.Millisecond, 4, 64 * 1024}, // at the ceiling, iterations grow
+	}
+	for _, tt := range tests {
+		params, _, err := splitBudget(tt.budget, 1024, ceiling, measure)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if params.Time != tt.time || params.Memory != tt.memoryKB {
+			t.Errorf("budget %s: expected t=%d m=%d, got t=%d m=%d", tt.budget, tt.time, tt.memoryKB, params.Time, params.Memory)
+		}
+	}
+
+	if _, _, err := splitBudget(time.Microsecond, 1024, ceiling, measure); err == nil {
+		t.Error("expected error when the minimum memory does not fit")
+	}
+}
+
+func TestSplitBudget(t *testing.T) {
+	old := SplitBudgetMaxMemory
+	SplitBudgetMaxMemory = 8 * 1024
+	t.Cleanup(func() { SplitBudgetMaxMemory = old })
+
+	timeIterations, memoryKiB := SplitBudget(50*time.Millisecond, 1024)
+	if memoryKiB < 1024 || memoryKiB > SplitBudgetMaxMemory {
+		t.Errorf("memory %d KB outside %d..%d", memoryKiB, 1024, SplitBudgetMaxMemory)
+	}
+	if timeIterations < MinTime || timeIterations > MaxTime {
+		t.Errorf("time %d outside limits", timeIterations)
+	}
+
+	if timeIterations, memoryKiB := SplitBudget(time.Nanosecond, 2048); timeIterations != MinTime || memoryKiB != 2048 {
+		t.Errorf("expected the minimums for an unreachable budget, got t=%d m=%d", timeIterations, memoryKiB)
+	}
+}
+
+func TestCalibrate(t *testing.T) {
+	params, err := Calibrate(50*time.Millisecond, 1024)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if params.Memory != 1024 || params.Time < MinTime || params.Time > MaxTime {
+		t.Errorf("unexpected params %+v", params)
+	}
+	if _, err := GenerateFromPassword([]byte("test"), params); err != nil {
+		t.Errorf("calibrated params rejected by GenerateFromPassword: %v", err)
+	}
+
+	if _, err := Calibrate(0, 1024); err == nil {
+		t.Error("expected error for zero target")
+	}
+	if _, err := Calibrate(time.Nanosecond, 1024); err == nil {
+		t.Error("expected error for unreachable target")
+	}
+}
+
+func TestNextCalibrationTime(t *testing.T) {
+	tests := []struct {
+		fits, exceeds, next uint32
+		done                bool
+	}{
+		{1, 0, 2, false},
+		{64, 0, MaxTime, false},
+		{MaxTime, 0, 0, true},
+		{0, 1, 0, true},
+		{8, 16, 12, false},
+		{12, 13, 0, true},
+	}
+	for _, tt := range tests {
+		next, done := nextCalibrationTime(tt.fits, tt.exceeds)
+		if next != tt.next || done != tt.done {
+			t.Errorf("nextCalibrationTime(%d, %d) = %d, %v; want %d, %v", tt.fits, tt.exceeds, next, done, tt.next, tt.done)
+		}
+	}
+}
+
+func TestCalibrateContextCancelled(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	// Every sample takes 5ms on the fake clock; cancel during the third (Time 4)
+	now := time.Unix(0, 0)
+	calls := 0
+	SetTimeSource(func() time.Time {
+		calls++
+		if calls == 6 {
+			cancel()
+		}
+		now = now.Add(5 * time.Millisecond)
+		return now
+	})
+	t.Cleanup(func() { SetTimeSource(nil) })
+
+	params, err := CalibrateContext(ctx, time.Second, 1024)
+	if err != context.Canceled {
+		t.Fatalf("expected %v, got %v", context.Canceled, err)
+	}
+	if params == nil || params.Time != 4 {
+		t.Errorf("expected the best params so far (Time 4), got %+v", params)
+	}
+
+	// Cancelled before the first sample: nothing to return
+	params, err = CalibrateContext(ctx, time.Second, 1024)
+	if err != context.Canceled || params != nil {
+		t.Errorf("expected nil params and %v, got %+v, %v", context.Canceled, params, err)
+	}
+}
+
+func TestPlanHash(t *testing.T) {
+	params := &Params{Time: 1, Memory: 1024, Threads: 1, KeyLen: 32, Extra: "x=42"}
+	effective, encodedLen, estMemory, estLatency, err := PlanHash(params)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if effective == params || *effective != *params {
+		t.Errorf("expected a copy of %+v, got %+v", params, effective)
+	}
+	if estMemory != EstimateMemory(params) {
+		t.Errorf("expected %d bytes, got %d", EstimateMemory(params), estMemory)
+	}
+	if estLatency <= 0 {
+		t.Errorf("expected a positive latency, got %s", estLatency)
+	}
+
+	hash, err := GenerateFromPassword([]byte("password"), params)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if encodedLen != len(hash) {
+		t.Errorf("expected encoded length %d, got %d", len(hash), encodedLen)
+	}
+
+	// Out-of-range costs are clamped to what GenerateFromPassword accepts
+	effective, _, _, _, err = PlanHash(&Params{Time: 0, Memory: 1, Threads: 1, KeyLen: MaxKeyLen + 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if effective.Time != MinTime || effective.Memory != MinMemory || effective.KeyLen != MaxKeyLen {
+		t.Errorf("expected costs clamped to the limits, got %+v", effective)
+	}
+	if err := validateParams(effective); err != nil {
+		t.Errorf("expected clamped params to validate, got %v", err)
+	}
+
+	if _, _, _, _, err := PlanHash(&Params{Time: 1, Memory: 1024, Threads: 1, KeyLen: 32, Encoding: EncodingBinary + 1}); err == nil {
+		t.Error("expected error for invalid params")
+	}
+}
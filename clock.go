@@ -0,0 +1,41 @@
+package argon2id
+
+import (
+	"sync/atomic"
+	"time"
+)
+
+// timeSource holds the clock set by SetTimeSource, or nil for time.Now. It
+// is read on every hash, so it is atomic to keep SetTimeSource race-free.
+var timeSource atomic.Pointer[func() time.Time]
+
+// nowFunc reads the clock set by SetTimeSource. Everything in the package
+// that reads the current time calls it.
+func nowFunc() time.Time {
+	if now := timeSource.Load(); now != nil {
+		return (*now)()
+	}
+	return time.Now()
+}
+
+// SetTimeSource replaces the clock used by everything in the package that
+// reads the current time, such as hash timing (MeasureHashTime,
+// RecommendParams, CompareHashAndPasswordTimed), LoadShedder, VerifyCache
+// expiry and the Metadata creation time and age checks; a nil now restores
+// time.Now.
+//
+// It exists so tests of timing-dependent code can run deterministically
+// without real sleeps, and is not meant for production use. It is safe to
+// call concurrently with hashing, but a measurement in progress may read
+// both clocks, so set it up before the code under test runs and restore it
+// in a cleanup:
+//
+//	argon2id.SetTimeSource(fakeClock.Now)
+//	t.Cleanup(func() { argon2id.SetTimeSource(nil) })
+func SetTimeSource(now func() time.Time) {
+	if now == nil {
+		timeSource.Store(nil)
+		return
+	}
+	timeSource.Store(&now)
+}
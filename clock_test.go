@@ -0,0 +1,48 @@
+//go:build !fips && !goexperiment.boringcrypto
+
+package argon2id
+
+import (
+	"testing"
+	"time"
+)
+
+func TestSetTimeSource(t *testing.T) {
+	// Each reading advances the fake clock by a fixed step
+	now := time.Unix(0, 0)
+	SetTimeSource(func() time.Time {
+		now = now.Add(250 * time.Millisecond)
+		return now
+	})
+	t.Cleanup(func() { SetTimeSource(nil) })
+
+	elapsed, err := MeasureHashTime(&Params{Time: 1, Memory: 1024, Threads: 1, KeyLen: 32})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if elapsed != 250*time.Millisecond {
+		t.Errorf("expected 250ms from the fake clock, got %s", elapsed)
+	}
+
+	SetTimeSource(nil)
+	if got := nowFunc(); time.Since(got) > time.Minute {
+		t.Errorf("expected nil to restore time.Now, got %s", got)
+	}
+}
+
+func TestSetTimeSourceConcurrent(t *testing.T) {
+	t.Cleanup(func() { SetTimeSource(nil) })
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for range 100 {
+			SetTimeSource(time.Now)
+			SetTimeSource(nil)
+		}
+	}()
+	for range 100 {
+		nowFunc()
+	}
+	<-done
+}
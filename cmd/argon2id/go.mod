@@ -0,0 +1,15 @@
+module github.com/sixcolors/argon2id/cmd/argon2id
+
+go 1.24.3
+
+require (
+	github.com/sixcolors/argon2id v1.0.0
+	golang.org/x/term v0.34.0
+)
+
+require (
+	golang.org/x/crypto v0.41.0 // indirect
+	golang.org/x/sys v0.35.0 // indirect
+)
+
+replace github.com/sixcolors/argon2id => ../..
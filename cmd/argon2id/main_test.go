@@ -0,0 +1,97 @@
+package main
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/sixcolors/argon2id"
+)
+
+func TestHashStdin(t *testing.T) {
+	var stdout, stderr bytes.Buffer
+	code := run([]string{"hash", "-t", "1", "-m", "1024", "-p", "1", "--stdin"}, strings.NewReader("s3cret\n"), &stdout, &stderr)
+	if code != exitOK {
+		t.Fatalf("expected exit %d, got %d: %s", exitOK, code, stderr.String())
+	}
+
+	hash := bytes.TrimSpace(stdout.Bytes())
+	if err := argon2id.CompareHashAndPassword(hash, []byte("s3cret")); err != nil {
+		t.Errorf("expected %s to verify the line without its newline, got %v", hash, err)
+	}
+}
+
+func TestHashReferenceFlags(t *testing.T) {
+	var stdout, stderr bytes.Buffer
+	code := run([]string{"hash", "-t", "1", "-k", "2048", "-p", "1", "-l", "16", "--stdin"}, strings.NewReader("s3cret\n"), &stdout, &stderr)
+	if code != exitOK {
+		t.Fatalf("expected exit %d, got %d: %s", exitOK, code, stderr.String())
+	}
+
+	params, err := argon2id.ExtractParams(bytes.TrimSpace(stdout.Bytes()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if params.Memory != 2048 || params.KeyLen != 16 {
+		t.Errorf("expected -k to set memory and -l the key length, got %+v", params)
+	}
+}
+
+func TestVerifyStdin(t *testing.T) {
+	hash, err := argon2id.GenerateFromPassword([]byte("s3cret"), &argon2id.Params{Time: 1, Memory: 1024, Threads: 1, KeyLen: 32})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name  string
+		input string
+		want  int
+	}{
+		{"match", "s3cret\n", exitOK},
+		{"match with CRLF", "s3cret\r\n", exitOK},
+		{"match without newline", "s3cret", exitOK},
+		{"only first line", "s3cret\nignored\n", exitOK},
+		{"mismatch", "wrong\n", exitMismatch},
+		{"empty stdin", "", exitError},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var stdout, stderr bytes.Buffer
+			code := run([]string{"verify", "--stdin", string(hash)}, strings.NewReader(tt.input), &stdout, &stderr)
+			if code != tt.want {
+				t.Errorf("expected exit %d, got %d (stdout %q, stderr %q)", tt.want, code, stdout.String(), stderr.String())
+			}
+		})
+	}
+}
+
+func TestNonTerminalStdinRequiresFlag(t *testing.T) {
+	var stdout, stderr bytes.Buffer
+	code := run([]string{"hash"}, strings.NewReader("s3cret\n"), &stdout, &stderr)
+	if code != exitError {
+		t.Errorf("expected exit %d, got %d", exitError, code)
+	}
+	if !strings.Contains(stderr.String(), "--stdin") {
+		t.Errorf("expected the error to suggest --stdin, got %q", stderr.String())
+	}
+	if stdout.Len() != 0 {
+		t.Errorf("expected no hash to be printed, got %q", stdout.String())
+	}
+}
+
+func TestUsageErrors(t *testing.T) {
+	for _, args := range [][]string{
+		nil,
+		{"bogus"},
+		{"verify", "--stdin"},
+		{"hash", "-t", "1000", "--stdin"},
+		{"hash", "-nope"},
+		{"hash", "-m", "1024", "-k", "1024", "--stdin"},
+	} {
+		var stdout, stderr bytes.Buffer
+		if code := run(args, strings.NewReader("s3cret\n"), &stdout, &stderr); code != exitError {
+			t.Errorf("%q: expected exit %d, got %d", args, exitError, code)
+		}
+	}
+}
@@ -0,0 +1,25 @@
+package argon2id
+
+// HashCodec converts between PHC hashes and the form they are stored in,
+// e.g. an envelope with a version byte encrypted by a secret manager such as
+// Vault or a KMS. Set it as Hasher.Codec: the Hasher encodes every hash it
+// generates and decodes stored values before verifying them. Decode must
+// invert Encode.
+//
+// Implementations must be safe for concurrent use. An error from Decode is
+// returned by verification as is, so a stored value the codec cannot open
+// is distinguishable from a wrong password.
+type HashCodec interface {
+	Encode(phc []byte) ([]byte, error)
+	Decode(stored []byte) ([]byte, error)
+}
+
+// IdentityCodec is the HashCodec that stores hashes unchanged. A Hasher
+// without a Codec behaves as if it used IdentityCodec.
+type IdentityCodec struct{}
+
+// Encode returns phc unchanged.
+func (IdentityCodec) Encode(phc []byte) ([]byte, error) { return phc, nil }
+
+// Decode returns stored unchanged.
+func (IdentityCodec) Decode(stored []byte) ([]byte, error) { return stored, nil }
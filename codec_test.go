@@ -0,0 +1,105 @@
+//go:build !fips && !goexperiment.boringcrypto
+
+package argon2id
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+)
+
+// envelopeCodec is a toy secret-manager envelope: a version byte followed
+// by the hash XORed with a key byte.
+type envelopeCodec struct {
+	version, key byte
+}
+
+var errUnknownEnvelope = errors.New("unknown envelope version")
+
+func (c envelopeCodec) Encode(phc []byte) ([]byte, error) {
+	stored := []byte{c.version}
+	for _, b := range phc {
+		stored = append(stored, b^c.key)
+	}
+	return stored, nil
+}
+
+func (c envelopeCodec) Decode(stored []byte) ([]byte, error) {
+	if len(stored) == 0 || stored[0] != c.version {
+		return nil, errUnknownEnvelope
+	}
+	phc := make([]byte, 0, len(stored)-1)
+	for _, b := range stored[1:] {
+		phc = append(phc, b^c.key)
+	}
+	return phc, nil
+}
+
+func TestHasherCodec(t *testing.T) {
+	weak := &Params{Time: 1, Memory: 1024, Threads: 1, KeyLen: 32}
+	codec := envelopeCodec{version: 1, key: 0x5a}
+
+	h := NewHasher(weak)
+	h.Codec = codec
+	stored, err := h.GenerateFromPassword([]byte("password"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if stored[0] != 1 || bytes.Contains(stored, []byte("$argon2id$")) {
+		t.Errorf("expected an enveloped hash, got %q", stored)
+	}
+	if err := CompareHashAndPassword(stored, []byte("password")); err == nil {
+		t.Error("expected the enveloped hash not to verify without the codec")
+	}
+
+	if err := h.CompareHashAndPassword(stored, []byte("password")); err != nil {
+		t.Errorf("expected match, got %v", err)
+	}
+	if err := h.CompareHashAndPassword(stored, []byte("wrong")); err != ErrMismatchedHashAndPassword {
+		t.Errorf("expected ErrMismatchedHashAndPassword, got %v", err)
+	}
+	result, err := h.VerifyDetailed(stored, []byte("password"))
+	if err != nil || !result.Matched {
+		t.Errorf("expected VerifyDetailed match, got %+v, %v", result, err)
+	}
+
+	// Codec errors are returned as is
+	other := NewHasher(weak)
+	other.Codec = envelopeCodec{version: 2, key: 0x5a}
+	if err := other.CompareHashAndPassword(stored, []byte("password")); err != errUnknownEnvelope {
+		t.Errorf("expected errUnknownEnvelope, got %v", err)
+	}
+
+	// Upgraded hashes are enveloped too
+	strong := NewHasher(&Params{Time: 2, Memory: 1024, Threads: 1, KeyLen: 32})
+	strong.Codec = codec
+	newHash, err := strong.CompareAndUpgrade(stored, []byte("password"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if newHash == nil || newHash[0] != 1 {
+		t.Fatalf("expected an enveloped upgraded hash, got %q", newHash)
+	}
+	phc, err := codec.Decode(newHash)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if params, err := ExtractParams(phc); err != nil || params.Time != 2 {
+		t.Errorf("expected upgraded params with t=2, got %+v, %v", params, err)
+	}
+}
+
+func TestIdentityCodec(t *testing.T) {
+	h := NewHasher(&Params{Time: 1, Memory: 1024, Threads: 1, KeyLen: 32})
+	h.Codec = IdentityCodec{}
+	hash, err := h.GenerateFromPassword([]byte("password"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !IsArgon2idHash(hash) {
+		t.Errorf("expected a plain PHC hash, got %s", hash)
+	}
+	if err := h.CompareHashAndPassword(hash, []byte("password")); err != nil {
+		t.Errorf("expected match, got %v", err)
+	}
+}
@@ -0,0 +1,85 @@
+package argon2id
+
+import "encoding/base64"
+
+// ConstantTimeDecode hardens the decoding of PHC salt and hash segments
+// against timing side channels.
+//
+// encoding/base64 decodes with table lookups and stops at the end of its
+// input, so the time it takes varies with the length of the salt and digest
+// segments, i.e. with the structure of the stored hash. When set, both
+// segments are instead copied into a buffer of fixed size (the encoded
+// length of a MaxKeyLen digest) and decoded in full with branch-free,
+// table-free arithmetic, so the decode takes the same time for every hash
+// that fits. Only the unpadded standard alphabet is accepted in this mode:
+// the padded, hex and Params.Encoder fallbacks are variable-time and are
+// disabled, and segments they would have accepted return ErrInvalidHash.
+//
+// It closes only the base64 decode leak. Splitting the PHC string and
+// parsing the parameters remain variable-time, and the lengths are in any
+// case visible to anyone who can read the stored hash. It defaults to false;
+// set it once during initialization, before any hashes are decoded.
+var ConstantTimeDecode bool
+
+// ctSegmentLen is the fixed length constant-time decoding pads segments to:
+// the unpadded base64 length of a MaxKeyLen digest.
+var ctSegmentLen = base64.RawStdEncoding.EncodedLen(MaxKeyLen)
+
+// decodeBase64ConstantTime decodes an unpadded standard base64 segment in
+// time independent of its length and contents, for segments of at most
+// ctSegmentLen characters. ok is false for invalid segments.
+func decodeBase64ConstantTime(segment string) (decoded []byte, ok bool) {
+	n := len(segment)
+	if n > ctSegmentLen {
+		return nil, false
+	}
+
+	// Positions past the segment decode as 'A' (zero bits), which leaves
+	// the decoded prefix unchanged
+	buf := make([]byte, ctSegmentLen)
+	for i := range buf {
+		buf[i] = 'A'
+	}
+	copy(buf, segment)
+
+	out := make([]byte, ctSegmentLen*6/8)
+	var acc, bits, j, bad int
+	for i, c := range buf {
+		value, valid := ctDecodeChar(int(c))
+		bad |= ctLess(i, n) & (1 - valid)
+
+		acc = acc<<6 | value
+		bits += 6
+		if bits >= 8 {
+			bits -= 8
+			out[j] = byte(acc >> bits)
+			j++
+		}
+	}
+
+	if bad != 0 || n%4 == 1 {
+		return nil, false
+	}
+	return out[:n*6/8], true
+}
+
+// ctDecodeChar returns the 6-bit value of a standard base64 character and
+// whether it is one, without branches or table lookups.
+func ctDecodeChar(c int) (value, valid int) {
+	upper := ctInRange(c, 'A', 'Z')
+	lower := ctInRange(c, 'a', 'z')
+	digit := ctInRange(c, '0', '9')
+	plus := ctEqual(c, '+')
+	slash := ctEqual(c, '/')
+
+	value = -upper&(c-'A') | -lower&(c-'a'+26) | -digit&(c-'0'+52) | -plus&62 | -slash&63
+	return value, upper | lower | digit | plus | slash
+}
+
+// The helpers below return 1 or 0 for byte-sized, non-negative x and y.
+
+func ctLess(x, y int) int { return ((x - y) >> 8) & 1 }
+
+func ctEqual(x, y int) int { return ((x ^ y) - 1) >> 8 & 1 }
+
+func ctInRange(x, lo, hi int) int { return (1 - ctLess(x, lo)) & (1 - ctLess(hi, x)) }
@@ -0,0 +1,107 @@
+//go:build !fips && !goexperiment.boringcrypto
+
+package argon2id
+
+import (
+	"bytes"
+	"crypto/rand"
+	"encoding/base64"
+	"flag"
+	"slices"
+	"testing"
+	"time"
+)
+
+// Run with: go test -run TestConstantTimeDecodeTiming . -timing
+var timing = flag.Bool("timing", false, "run wall-clock timing tests")
+
+func TestDecodeBase64ConstantTime(t *testing.T) {
+	for n := 0; n <= MaxKeyLen; n++ {
+		data := make([]byte, n)
+		if _, err := rand.Read(data); err != nil {
+			t.Fatal(err)
+		}
+		encoded := base64.RawStdEncoding.EncodeToString(data)
+
+		decoded, ok := decodeBase64ConstantTime(encoded)
+		if !ok || !bytes.Equal(decoded, data) {
+			t.Fatalf("%d bytes: expected %x, got %x (ok=%v)", n, data, decoded, ok)
+		}
+	}
+
+	for _, bad := range []string{"A", "AAAAA", "AA=A", "AA-A", "AA_A", "AA A", string(make([]byte, ctSegmentLen+1))} {
+		if _, ok := decodeBase64ConstantTime(bad); ok {
+			t.Errorf("expected %q to be rejected", bad)
+		}
+		_, err := base64.RawStdEncoding.DecodeString(bad)
+		if err == nil {
+			t.Errorf("expected encoding/base64 to reject %q too", bad)
+		}
+	}
+}
+
+func TestConstantTimeDecode(t *testing.T) {
+	hash, err := GenerateFromPassword([]byte("password"), &Params{Time: 1, Memory: 1024, Threads: 1, KeyLen: 32})
+	if err != nil {
+		t.Fatal(err)
+	}
+	salt := []byte("0123456789abcdef")
+	hexHash := []byte("$argon2id$v=19$m=1024,t=1,p=1$" +
+		"30313233343536373839616263646566$" +
+		"000102030405060708090a0b0c0d0e0f000102030405060708090a0b0c0d0e0f")
+
+	ConstantTimeDecode = true
+	t.Cleanup(func() { ConstantTimeDecode = false })
+
+	if err := CompareHashAndPassword(hash, []byte("password")); err != nil {
+		t.Errorf("expected hash to verify, got %v", err)
+	}
+	if err := CompareHashAndPassword(hash, []byte("wrong")); err != ErrMismatchedHashAndPassword {
+		t.Errorf("expected %v, got %v", ErrMismatchedHashAndPassword, err)
+	}
+
+	// Variable-time fallbacks are disabled
+	padded := []byte("$argon2id$v=19$m=1024,t=1,p=1$" + base64.StdEncoding.EncodeToString(salt) + "$AAAAAAAAAAAAAAAAAAAAAA")
+	for _, h := range [][]byte{hexHash, padded} {
+		if _, err := ExtractParams(h); err != ErrInvalidHash {
+			t.Errorf("expected %v for %s, got %v", ErrInvalidHash, h, err)
+		}
+	}
+}
+
+// TestConstantTimeDecodeTiming is a best-effort check that decoding time
+// does not depend on segment length. Timing is noisy, so it compares
+// medians with a generous tolerance, and it only runs with -timing on an
+// otherwise idle machine.
+func TestConstantTimeDecodeTiming(t *testing.T) {
+	if !*timing {
+		t.Skip("timing test skipped without -timing")
+	}
+
+	short := base64.RawStdEncoding.EncodeToString(make([]byte, MinKeyLen))
+	long := base64.RawStdEncoding.EncodeToString(make([]byte, MaxKeyLen))
+
+	sample := func(segment string) time.Duration {
+		start := time.Now()
+		for j := 0; j < 20; j++ {
+			decodeBase64ConstantTime(segment)
+		}
+		return time.Since(start)
+	}
+
+	// Interleave the samples so background load affects both alike
+	shortSamples := make([]time.Duration, 501)
+	longSamples := make([]time.Duration, len(shortSamples))
+	for i := range shortSamples {
+		shortSamples[i] = sample(short)
+		longSamples[i] = sample(long)
+	}
+	slices.Sort(shortSamples)
+	slices.Sort(longSamples)
+
+	shortTime, longTime := shortSamples[len(shortSamples)/2], longSamples[len(longSamples)/2]
+	ratio := float64(longTime) / float64(shortTime)
+	if ratio > 1.5 || ratio < 1/1.5 {
+		t.Errorf("decode time varies with length: %s for %d chars, %s for %d chars", shortTime, len(short), longTime, len(long))
+	}
+}
@@ -0,0 +1,150 @@
+package argon2id
+
+import (
+	"sync"
+	"sync/atomic"
+)
+
+// decodeCacheActive holds the decode cache, nil when disabled.
+var decodeCacheActive atomic.Pointer[decodeCache]
+
+// EnableDecodeCache makes verification remember the decoded parameters,
+// salt and digest of the size most recently verified hash strings, so
+// verifying the same hash again skips parsing it. A size <= 0 disables the
+// cache, which is the default; each call starts with an empty cache.
+//
+// It is meant for hot paths such as API keys, where the same hash is
+// verified on every request. Only parsing is skipped: argon2.IDKey still
+// runs on every verification, so the cost of guessing is unchanged. The
+// entries hold nothing that is not in the hashes themselves.
+//
+// Entries reflect the decoding settings (StrictDecode, ConstantTimeDecode,
+// RejectWeakSalt, OnNonstandardSalt) in effect when the hash was first
+// decoded, and OnNonstandardSalt is not called again on a hit: call
+// EnableDecodeCache again after changing them. Hashes that fail to decode
+// are not cached.
+func EnableDecodeCache(size int) {
+	if size <= 0 {
+		decodeCacheActive.Store(nil)
+		return
+	}
+	decodeCacheActive.Store(&decodeCache{
+		entries: make(map[decodeCacheKey]*decodedHash),
+		size:    size,
+	})
+}
+
+// decodeCacheKey identifies a decoding: the hash and the parts of the
+// fallback params that decodeHash uses.
+type decodeCacheKey struct {
+	hash    string
+	time    uint32
+	memory  uint32
+	threads uint8
+}
+
+// decodedHash is a cached decodeHash result, linked into the cache's
+// recency list. The salt and digest are shared between hits and must not
+// be modified.
+type decodedHash struct {
+	params     *Params
+	prev, next *decodedHash
+	salt       []byte
+	digest     []byte
+	key        decodeCacheKey
+}
+
+// decodeCache is a least-recently-used cache of decoded hashes. Entries
+// form a list from newest to oldest.
+type decodeCache struct {
+	entries        map[decodeCacheKey]*decodedHash
+	newest, oldest *decodedHash
+	mu             sync.Mutex
+	size           int
+}
+
+// decodeHashCached is decodeHash, served from the decode cache if enabled.
+// The returned params are a copy the caller may modify.
+func decodeHashCached(hash string, fallback *Params) (*Params, []byte, []byte, error) {
+	cache := decodeCacheActive.Load()
+	if cache == nil {
+		return decodeHash(hash, fallback)
+	}
+
+	key := decodeCacheKey{hash: hash}
+	if fallback != nil {
+		key.time, key.memory, key.threads = fallback.Time, fallback.Memory, fallback.Threads
+	}
+	if entry, ok := cache.get(key); ok {
+		return entry.params.Clone(), entry.salt, entry.digest, nil
+	}
+
+	params, salt, digest, err := decodeHash(hash, fallback)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	cache.put(&decodedHash{params: params.Clone(), salt: salt, digest: digest, key: key})
+	return params, salt, digest, nil
+}
+
+// get returns the entry for key and marks it as recently used.
+func (c *decodeCache) get(key decodeCacheKey) (*decodedHash, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	entry, ok := c.entries[key]
+	if ok {
+		c.unlink(entry)
+		c.pushNewest(entry)
+	}
+	return entry, ok
+}
+
+// put adds entry, evicting the least recently used one if the cache is full.
+func (c *decodeCache) put(entry *decodedHash) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if old, ok := c.entries[entry.key]; ok {
+		c.unlink(old)
+	} else if len(c.entries) >= c.size {
+		evicted := c.oldest
+		c.unlink(evicted)
+		delete(c.entries, evicted.key)
+	}
+	c.entries[entry.key] = entry
+	c.pushNewest(entry)
+}
+
+// unlink removes entry from the recency list.
+func (c *decodeCache) unlink(entry *decodedHash) {
+	if entry.prev != nil {
+		entry.prev.next = entry.next
+	} else {
+		c.newest = entry.next
+	}
+	if entry.next != nil {
+		entry.next.prev = entry.prev
+	} else {
+		c.oldest = entry.prev
+	}
+	entry.prev, entry.next = nil, nil
+}
+
+// pushNewest puts entry at the front of the recency list.
+func (c *decodeCache) pushNewest(entry *decodedHash) {
+	entry.next = c.newest
+	if c.newest != nil {
+		c.newest.prev = entry
+	} else {
+		c.oldest = entry
+	}
+	c.newest = entry
+}
+
+// count returns the number of cached entries.
+func (c *decodeCache) count() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return len(c.entries)
+}
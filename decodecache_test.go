@@ -0,0 +1,123 @@
+//go:build !fips && !goexperiment.boringcrypto
+
+package argon2id
+
+import "testing"
+
+func TestDecodeCache(t *testing.T) {
+	EnableDecodeCache(2)
+	t.Cleanup(func() { EnableDecodeCache(0) })
+	cache := decodeCacheActive.Load()
+
+	params := &Params{Time: 1, Memory: 1024, Threads: 1, KeyLen: 32}
+	hashes := make([][]byte, 3)
+	for i := range hashes {
+		hash, err := GenerateFromPassword([]byte("password"), params)
+		if err != nil {
+			t.Fatal(err)
+		}
+		hashes[i] = hash
+	}
+
+	for range 2 {
+		if err := CompareHashAndPassword(hashes[0], []byte("password")); err != nil {
+			t.Fatalf("expected match, got %v", err)
+		}
+		if err := CompareHashAndPassword(hashes[0], []byte("wrong")); err != ErrMismatchedHashAndPassword {
+			t.Fatalf("expected ErrMismatchedHashAndPassword, got %v", err)
+		}
+	}
+	if n := cache.count(); n != 1 {
+		t.Errorf("expected 1 entry, got %d", n)
+	}
+
+	// Callers get their own copy of the params
+	result, err := VerifyDetailed(hashes[0], []byte("password"), params)
+	if err != nil {
+		t.Fatal(err)
+	}
+	result.Params.Time = 99
+	result, err = VerifyDetailed(hashes[0], []byte("password"), params)
+	if err != nil || !result.Matched || result.Params.Time != 1 {
+		t.Errorf("cached params were modified: %+v, %v", result, err)
+	}
+
+	// The least recently used hash is evicted
+	for _, hash := range hashes[1:] {
+		if err := CompareHashAndPassword(hash, []byte("password")); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if n := cache.count(); n != 2 {
+		t.Errorf("expected 2 entries, got %d", n)
+	}
+	if _, ok := cache.get(decodeCacheKey{hash: string(hashes[0])}); ok {
+		t.Error("expected the oldest hash to be evicted")
+	}
+	if _, ok := cache.get(decodeCacheKey{hash: string(hashes[2])}); !ok {
+		t.Error("expected the newest hash to be cached")
+	}
+
+	// Hashes that fail to decode are not cached
+	if err := CompareHashAndPassword([]byte("invalid"), []byte("password")); err == nil {
+		t.Error("expected error for invalid hash")
+	}
+	if _, ok := cache.get(decodeCacheKey{hash: "invalid"}); ok {
+		t.Error("expected invalid hash not to be cached")
+	}
+
+	EnableDecodeCache(0)
+	if decodeCacheActive.Load() != nil {
+		t.Error("expected cache to be disabled")
+	}
+}
+
+func TestDecodeCacheFallback(t *testing.T) {
+	EnableDecodeCache(4)
+	t.Cleanup(func() { EnableDecodeCache(0) })
+
+	params := &Params{Time: 1, Memory: 1024, Threads: 1, KeyLen: 32, Encoding: EncodingRaw}
+	hash, err := GenerateFromPassword([]byte("password"), params)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// A compact hash decoded with other fallback params is a separate entry
+	if err := NewHasher(params).CompareHashAndPassword(hash, []byte("password")); err != nil {
+		t.Fatalf("expected match, got %v", err)
+	}
+	other := params.Clone()
+	other.Time = 2
+	if err := NewHasher(other).CompareHashAndPassword(hash, []byte("password")); err != ErrMismatchedHashAndPassword {
+		t.Errorf("expected ErrMismatchedHashAndPassword with other fallback params, got %v", err)
+	}
+	if n := decodeCacheActive.Load().count(); n != 2 {
+		t.Errorf("expected 2 entries, got %d", n)
+	}
+}
+
+// BenchmarkDecodeCache compares parsing a hash with serving it from the
+// decode cache; the Argon2 computation is not included.
+func BenchmarkDecodeCache(b *testing.B) {
+	hash := "$argon2id$v=19$m=65536,t=3,p=2$MDEyMzQ1Njc4OWFiY2RlZg$UqcjN8FEhR9C3aVPAePbwhjlawq6mqKGGOFCbIG7i8U"
+
+	b.Run("uncached", func(b *testing.B) {
+		b.ReportAllocs()
+		for b.Loop() {
+			if _, _, _, err := decodeHash(hash, nil); err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+
+	b.Run("cached", func(b *testing.B) {
+		EnableDecodeCache(1)
+		b.Cleanup(func() { EnableDecodeCache(0) })
+		b.ReportAllocs()
+		for b.Loop() {
+			if _, _, _, err := decodeHashCached(hash, nil); err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+}
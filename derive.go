@@ -0,0 +1,69 @@
+package argon2id
+
+import (
+	"crypto/hkdf"
+	"crypto/sha256"
+	"fmt"
+)
+
+// MinSaltLen is the minimum salt length in bytes allowed by the Argon2 specification.
+const MinSaltLen = 8
+
+// DeriveKey computes the raw Argon2ID key for password and a caller-supplied
+// salt, without generating a salt or encoding the result.
+//
+// It is intended for reproducing known test vectors and for interop checks
+// against other implementations. For password storage use
+// GenerateFromPassword, which generates a fresh random salt per hash.
+//
+// If params is nil, DefaultParams() will be used. The params are validated
+// like in GenerateFromPassword, and salt must be at least MinSaltLen bytes.
+func DeriveKey(password, salt []byte, params *Params) ([]byte, error) {
+	if params == nil {
+		params = DefaultParams()
+	}
+	if fipsRestricted {
+		return nil, ErrNotFIPSApproved
+	}
+	if err := validateParams(params); err != nil {
+		return nil, err
+	}
+	if len(salt) < MinSaltLen {
+		return nil, fmt.Errorf("argon2id: salt length (%d) is too short, must be >= %d", len(salt), MinSaltLen)
+	}
+
+	return idKey(password, salt, params), nil
+}
+
+// DeriveSubkeys runs Argon2 once on password and salt and expands the result
+// with HKDF-SHA256 into independent subkeys of the given lengths, e.g. for
+// several encryption keys, at the cost of a single Argon2 computation.
+//
+// Subkey i is expanded with the info label "argon2id subkey i", so each
+// is independent of the others and the same inputs always give the same
+// subkeys. params.KeyLen sets the length of the Argon2 output they are
+// expanded from, and should be at least 32. Keep the password hash used for
+// authentication separate: generate it with GenerateFromPassword and its
+// own random salt rather than deriving it here, so a stored hash never
+// reveals anything about the subkeys.
+//
+// The params and salt are checked as in DeriveKey. Each length must be
+// between 1 and 255*32 bytes.
+func DeriveSubkeys(password, salt []byte, params *Params, lengths []uint32) ([][]byte, error) {
+	master, err := DeriveKey(password, salt, params)
+	if err != nil {
+		return nil, err
+	}
+	defer clear(master)
+
+	subkeys := make([][]byte, len(lengths))
+	for i, n := range lengths {
+		if n == 0 || n > 255*sha256.Size {
+			return nil, fmt.Errorf("argon2id: subkey %d length (%d) must be between 1 and %d", i, n, 255*sha256.Size)
+		}
+		if subkeys[i], err = hkdf.Expand(sha256.New, master, fmt.Sprintf("argon2id subkey %d", i), int(n)); err != nil {
+			return nil, err
+		}
+	}
+	return subkeys, nil
+}
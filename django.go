@@ -0,0 +1,46 @@
+package argon2id
+
+import (
+	"bytes"
+	"fmt"
+)
+
+// djangoPrefix is the algorithm name Django's Argon2PasswordHasher puts in
+// front of the PHC string.
+const djangoPrefix = "argon2"
+
+// GenerateDjangoFormat is like GenerateFromPassword, but returns the hash in
+// the format of Django's Argon2PasswordHasher:
+// "argon2$argon2id$v=19$m=...,t=...,p=...$salt$hash". Django verifies such
+// hashes as they are, so they can be written to a Django-backed user table
+// during a migration. Django's must_update flags them for rehashing on
+// login if params differ from the hasher's configured costs.
+//
+// params must use EncodingPHC. If params is nil, DefaultParams() will be used.
+func GenerateDjangoFormat(password []byte, params *Params) ([]byte, error) {
+	if params != nil && params.Encoding != EncodingPHC {
+		return nil, fmt.Errorf("argon2id: Django format requires EncodingPHC, not %s", params.Encoding)
+	}
+	hash, err := GenerateFromPassword(password, params)
+	if err != nil {
+		return nil, err
+	}
+	return append([]byte(djangoPrefix), hash...), nil
+}
+
+// CompareDjangoFormat verifies a password against a hash stored by Django's
+// Argon2PasswordHasher (or GenerateDjangoFormat). Hashes without the
+// "argon2$" prefix return ErrInvalidHash; Django's older argon2i hashes
+// return ErrIncompatibleVariant. Otherwise it behaves like
+// CompareHashAndPassword.
+//
+// Django salts are random strings of 12 or 22 characters rather than
+// SaltLen bytes, so hashes created by Django itself only decode once
+// OnNonstandardSalt is set, which also reports them for rehashing.
+func CompareDjangoFormat(hashedPassword, password []byte) error {
+	hash, ok := bytes.CutPrefix(hashedPassword, []byte(djangoPrefix+"$"))
+	if !ok {
+		return ErrInvalidHash
+	}
+	return CompareHashAndPassword(append([]byte("$"), hash...), password)
+}
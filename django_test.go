@@ -0,0 +1,74 @@
+//go:build !fips && !goexperiment.boringcrypto
+
+package argon2id
+
+import (
+	"strings"
+	"testing"
+)
+
+// djangoVector is from Django's own test suite (auth_tests/test_hashers.py,
+// test_argon2_version_upgrade): the password "secret" hashed by
+// Argon2PasswordHasher with its defaults (m=102400, t=2, p=8) and a 16-byte
+// digest.
+const djangoVector = "argon2$argon2id$v=19$m=102400,t=2,p=8$Y041dExhNkljRUUy$TMa6A8fPJhCAUXRhJXCXdw"
+
+func TestCompareDjangoFormat(t *testing.T) {
+	// Django's 12-character salt is rejected unless nonstandard salts are allowed
+	if err := CompareDjangoFormat([]byte(djangoVector), []byte("secret")); err != ErrInvalidHash {
+		t.Errorf("expected ErrInvalidHash without OnNonstandardSalt, got %v", err)
+	}
+	var saltLen int
+	OnNonstandardSalt = func(length int) { saltLen = length }
+	t.Cleanup(func() { OnNonstandardSalt = nil })
+
+	if err := CompareDjangoFormat([]byte(djangoVector), []byte("secret")); err != nil {
+		t.Errorf("expected Django hash to verify, got %v", err)
+	}
+	if saltLen != 12 {
+		t.Errorf("expected OnNonstandardSalt to report 12, got %d", saltLen)
+	}
+	if err := CompareDjangoFormat([]byte(djangoVector), []byte("wrong")); err != ErrMismatchedHashAndPassword {
+		t.Errorf("expected ErrMismatchedHashAndPassword, got %v", err)
+	}
+
+	// Django's legacy argon2i hashes are recognized but not supported
+	argon2i := "argon2$argon2i$v=19$m=8,t=1,p=1$c2FsdHNhbHQ$YC9+jJCrQhs5R6db7LlN8Q"
+	if err := CompareDjangoFormat([]byte(argon2i), []byte("secret")); err != ErrIncompatibleVariant {
+		t.Errorf("expected ErrIncompatibleVariant, got %v", err)
+	}
+
+	for _, hash := range []string{
+		strings.TrimPrefix(djangoVector, "argon2"),
+		"bcrypt" + strings.TrimPrefix(djangoVector, "argon2"),
+		"argon2",
+		"",
+	} {
+		if err := CompareDjangoFormat([]byte(hash), []byte("secret")); err != ErrInvalidHash {
+			t.Errorf("%q: expected ErrInvalidHash, got %v", hash, err)
+		}
+	}
+}
+
+func TestGenerateDjangoFormat(t *testing.T) {
+	params := &Params{Time: 1, Memory: 1024, Threads: 1, KeyLen: 32}
+	hash, err := GenerateDjangoFormat([]byte("secret"), params)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasPrefix(string(hash), "argon2$argon2id$v=19$m=1024,t=1,p=1$") {
+		t.Errorf("unexpected Django hash %s", hash)
+	}
+	if err := CompareDjangoFormat(hash, []byte("secret")); err != nil {
+		t.Errorf("expected match, got %v", err)
+	}
+	if err := CompareHashAndPassword([]byte(strings.TrimPrefix(string(hash), "argon2")), []byte("secret")); err != nil {
+		t.Errorf("expected stripped hash to verify, got %v", err)
+	}
+
+	raw := params.Clone()
+	raw.Encoding = EncodingRaw
+	if _, err := GenerateDjangoFormat([]byte("secret"), raw); err == nil {
+		t.Error("expected error for EncodingRaw")
+	}
+}
@@ -0,0 +1,272 @@
+package argon2id
+
+import (
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// EncodingMode selects how a hash is serialized.
+//
+// The PHC string is self-describing and should be preferred. The compact
+// modes exist for systems with fixed-width hash columns that cannot store
+// it; they omit the Time, Memory and Threads parameters, so the verifier
+// must already know them (see Hasher and CompareHashAndPassword).
+type EncodingMode uint8
+
+const (
+	// EncodingPHC is the standard $argon2id$v=19$m=...,t=...,p=...$salt$hash string.
+	EncodingPHC EncodingMode = iota
+
+	// EncodingRaw is the hex-encoded salt and digest joined by a dot.
+	EncodingRaw
+
+	// EncodingBinary is a zero byte followed by the raw salt and digest.
+	EncodingBinary
+)
+
+// binaryPrefix marks EncodingBinary hashes. PHC strings always start with
+// '$' and raw hashes with a hex digit, so detection is unambiguous.
+const binaryPrefix = 0x00
+
+// String returns the name of the encoding mode.
+func (m EncodingMode) String() string {
+	switch m {
+	case EncodingPHC:
+		return "phc"
+	case EncodingRaw:
+		return "raw"
+	case EncodingBinary:
+		return "binary"
+	default:
+		return fmt.Sprintf("EncodingMode(%d)", uint8(m))
+	}
+}
+
+// encodeHash serializes the salt and digest according to params.Encoding
+func encodeHash(params *Params, salt, digest []byte) []byte {
+	switch params.Encoding {
+	case EncodingRaw:
+		return []byte(hex.EncodeToString(salt) + "." + hex.EncodeToString(digest))
+	case EncodingBinary:
+		hash := make([]byte, 0, 1+len(salt)+len(digest))
+		hash = append(hash, binaryPrefix)
+		hash = append(hash, salt...)
+		return append(hash, digest...)
+	default:
+		encoder := params.Encoder
+		if encoder == nil {
+			encoder = base64.RawStdEncoding
+		}
+
+		// Format: $argon2id$v=19$m=memory,t=time,p=threads$salt$hash
+		encodedSalt := encoder.EncodeToString(salt)
+		encodedHash := encoder.EncodeToString(digest)
+
+		known := fmt.Sprintf("m=%d,t=%d,p=%d", params.Memory, params.Time, params.Threads)
+		if extra := encodedExtra(params); extra != "" {
+			known += "," + extra
+		}
+		return []byte("$argon2id$v=19$" + known + "$" + encodedSalt + "$" + encodedHash)
+	}
+}
+
+// encodedExtra returns the parameters to encode after m, t and p for
+// params: its Extra without any stale alphabet or PostHash marker and with
+// an "l=" key length updated to KeyLen, plus the alphabet of a custom
+// Encoder and the PostHash marker if they are set.
+func encodedExtra(params *Params) string {
+	var kept []string
+	if params.Extra != "" {
+		for _, param := range strings.Split(params.Extra, ",") {
+			switch {
+			case param == postHashMarker, strings.HasPrefix(param, alphabetParam):
+			case strings.HasPrefix(param, "l="):
+				kept = append(kept, "l="+strconv.FormatUint(uint64(params.KeyLen), 10))
+			default:
+				kept = append(kept, param)
+			}
+		}
+	}
+	if params.Encoder != nil {
+		kept = append(kept, alphabetParam+encoderAlphabet(params.Encoder))
+	}
+	if params.PostHash != nil {
+		kept = append(kept, postHashMarker)
+	}
+	return strings.Join(kept, ",")
+}
+
+// alphabetParam is the PHC parameter recording the alphabet of a custom
+// Params.Encoder.
+const alphabetParam = "alphabet="
+
+// alphabetProbe is the 48 bytes whose base64 encoding is each of the 64
+// alphabet characters in order.
+var alphabetProbe, _ = base64.StdEncoding.DecodeString("ABCDEFGHIJKLMNOPQRSTUVWXYZabcdefghijklmnopqrstuvwxyz0123456789+/")
+
+// encoderAlphabet returns the 64-character alphabet of enc.
+func encoderAlphabet(enc *base64.Encoding) string {
+	return enc.EncodeToString(alphabetProbe)
+}
+
+// validAlphabet reports whether alphabet is 64 distinct printable ASCII
+// characters that do not delimit PHC strings.
+func validAlphabet(alphabet string) bool {
+	if len(alphabet) != 64 {
+		return false
+	}
+	var seen [128]bool
+	for i := 0; i < len(alphabet); i++ {
+		c := alphabet[i]
+		if c <= ' ' || c > '~' || c == '$' || c == ',' || c == '=' || seen[c] {
+			return false
+		}
+		seen[c] = true
+	}
+	return true
+}
+
+// alphabetEncoders caches the encodings built by recordedEncoder, keyed by
+// alphabet, so that hashes with the same alphabet decode to Params with the
+// same Encoder (e.g. one ParamHistogram key) without building it each time.
+// Past maxAlphabetEncoders alphabets, which no real column has, encodings
+// are built per decode so that untrusted hashes cannot grow the cache.
+var alphabetEncoders struct {
+	sync.Mutex
+	byAlphabet map[string]*base64.Encoding
+}
+
+const maxAlphabetEncoders = 16
+
+// recordedEncoder returns the encoding for the alphabet recorded in decoded
+// Extra parameters, or nil if there is none.
+func recordedEncoder(extra string) (*base64.Encoding, error) {
+	for rest, more := extra, extra != ""; more; {
+		var param string
+		param, rest, more = strings.Cut(rest, ",")
+		alphabet, ok := strings.CutPrefix(param, alphabetParam)
+		if !ok {
+			continue
+		}
+		if !validAlphabet(alphabet) {
+			return nil, ErrInvalidHash
+		}
+
+		alphabetEncoders.Lock()
+		defer alphabetEncoders.Unlock()
+		if enc, ok := alphabetEncoders.byAlphabet[alphabet]; ok {
+			return enc, nil
+		}
+		enc := base64.NewEncoding(alphabet).WithPadding(base64.NoPadding)
+		if alphabetEncoders.byAlphabet == nil {
+			alphabetEncoders.byAlphabet = make(map[string]*base64.Encoding)
+		}
+		if len(alphabetEncoders.byAlphabet) < maxAlphabetEncoders {
+			alphabetEncoders.byAlphabet[alphabet] = enc
+		}
+		return enc, nil
+	}
+	return nil, nil
+}
+
+// EncodedLength returns the exact length in bytes of the hash that
+// GenerateFromPassword produces for params, e.g. to size a VARCHAR column.
+//
+// For PHC strings the length depends on the number of digits in the Time,
+// Memory and Threads values as well as on KeyLen and any Extra parameters.
+// A PostHash transform that changes the digest length is not accounted
+// for. If params is nil, DefaultParams() will be used. params are not
+// validated.
+func EncodedLength(params *Params) int {
+	if params == nil {
+		params = DefaultParams()
+	}
+
+	keyLen := int(params.KeyLen)
+	switch params.Encoding {
+	case EncodingRaw:
+		return hex.EncodedLen(SaltLen) + 1 + hex.EncodedLen(keyLen)
+	case EncodingBinary:
+		return 1 + SaltLen + keyLen
+	default:
+		encoder := params.Encoder
+		if encoder == nil {
+			encoder = base64.RawStdEncoding
+		}
+		n := len("$argon2id$v=19$m=,t=,p=$$") +
+			len(strconv.FormatUint(uint64(params.Memory), 10)) +
+			len(strconv.FormatUint(uint64(params.Time), 10)) +
+			len(strconv.FormatUint(uint64(params.Threads), 10)) +
+			encoder.EncodedLen(SaltLen) +
+			encoder.EncodedLen(keyLen)
+		if extra := encodedExtra(params); extra != "" {
+			n += 1 + len(extra)
+		}
+		return n
+	}
+}
+
+// MaxEncodedLength returns the largest EncodedLength of candidates, the
+// column width that fits a hash generated with any of them, e.g. when
+// per-tier params share one column. A nil entry stands for DefaultParams().
+// It returns 0 for no candidates.
+func MaxEncodedLength(candidates []*Params) int {
+	n := 0
+	for _, params := range candidates {
+		n = max(n, EncodedLength(params))
+	}
+	return n
+}
+
+// detectEncoding reports the encoding mode of a serialized hash. Anything
+// that is neither binary nor raw is treated as PHC.
+func detectEncoding(hash string) EncodingMode {
+	if len(hash) > 0 && hash[0] == binaryPrefix {
+		return EncodingBinary
+	}
+	if salt, digest, ok := strings.Cut(hash, "."); ok && isHex(salt) && isHex(digest) {
+		return EncodingRaw
+	}
+	return EncodingPHC
+}
+
+// decodeRaw parses an EncodingRaw hash
+func decodeRaw(hash string) ([]byte, []byte, error) {
+	encodedSalt, encodedDigest, _ := strings.Cut(hash, ".")
+
+	salt, err := hex.DecodeString(encodedSalt)
+	if err != nil {
+		return nil, nil, ErrInvalidHash
+	}
+	digest, err := hex.DecodeString(encodedDigest)
+	if err != nil {
+		return nil, nil, ErrInvalidHash
+	}
+	return salt, digest, nil
+}
+
+// decodeBinary parses an EncodingBinary hash
+func decodeBinary(hash string) ([]byte, []byte, error) {
+	if len(hash) < 1+SaltLen+MinKeyLen {
+		return nil, nil, ErrHashTooShort
+	}
+	return []byte(hash[1 : 1+SaltLen]), []byte(hash[1+SaltLen:]), nil
+}
+
+// isHex reports whether s is a non-empty string of lowercase hex digits
+func isHex(s string) bool {
+	if s == "" {
+		return false
+	}
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		if (c < '0' || c > '9') && (c < 'a' || c > 'f') {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,220 @@
+//go:build !fips && !goexperiment.boringcrypto
+
+package argon2id
+
+import (
+	"encoding/base64"
+	"regexp"
+	"strings"
+	"testing"
+)
+
+func TestEncodingModes(t *testing.T) {
+	tests := []struct {
+		name    string
+		pattern string
+		mode    EncodingMode
+	}{
+		{"phc", `^\$argon2id\$v=19\$m=1024,t=1,p=1\$[A-Za-z0-9+/]{22}\$[A-Za-z0-9+/]{43}$`, EncodingPHC},
+		{"raw", `^[0-9a-f]{32}\.[0-9a-f]{64}$`, EncodingRaw},
+		{"binary", "", EncodingBinary},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			h := NewHasher(&Params{Time: 1, Memory: 1024, Threads: 1, KeyLen: 32, Encoding: tt.mode})
+
+			hash, err := h.GenerateFromPassword([]byte("password"))
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if tt.mode == EncodingBinary {
+				if len(hash) != 1+SaltLen+32 || hash[0] != 0 {
+					t.Errorf("hash %q not in %s format", hash, tt.mode)
+				}
+			} else if !regexp.MustCompile(tt.pattern).Match(hash) {
+				t.Errorf("hash %q not in %s format", hash, tt.mode)
+			}
+			if mode := detectEncoding(string(hash)); mode != tt.mode {
+				t.Errorf("detected %s, expected %s", mode, tt.mode)
+			}
+
+			if err := h.CompareHashAndPassword(hash, []byte("password")); err != nil {
+				t.Errorf("expected password and hash to match, got %v", err)
+			}
+			if err := h.CompareHashAndPassword(hash, []byte("wrong")); err != ErrMismatchedHashAndPassword {
+				t.Errorf("expected %v, got %v", ErrMismatchedHashAndPassword, err)
+			}
+		})
+	}
+}
+
+func TestEncodingModesDefaultParams(t *testing.T) {
+	for _, mode := range []EncodingMode{EncodingRaw, EncodingBinary} {
+		params := DefaultParams()
+		params.Encoding = mode
+
+		hash, err := GenerateFromPassword([]byte("password"), params)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		// Compact hashes verify with the default cost at package level
+		if err := CompareHashAndPassword(hash, []byte("password")); err != nil {
+			t.Errorf("%s: expected password and hash to match, got %v", mode, err)
+		}
+
+		extracted, err := ExtractParams(hash)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if *extracted != *params {
+			t.Errorf("%s: expected %+v, got %+v", mode, params, extracted)
+		}
+	}
+}
+
+func TestEncodingInvalid(t *testing.T) {
+	if _, err := GenerateFromPassword([]byte("password"), &Params{Time: 1, Memory: 1024, Threads: 1, KeyLen: 32, Encoding: 3}); err == nil {
+		t.Error("expected error for unknown encoding")
+	}
+
+	tests := []struct {
+		name    string
+		hash    string
+		wantErr error
+	}{
+		{"raw short salt", "00112233.0011223344556677", ErrInvalidHash},
+		{"raw odd length", "0011223344556677889900112233445.00112233", ErrInvalidHash},
+		{"binary too short", "\x000123456789abcdef", ErrHashTooShort},
+		{"uppercase hex is not raw", "0011223344556677889900112233445F.00112233", ErrInvalidHash},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := CompareHashAndPassword([]byte(tt.hash), []byte("password")); err != tt.wantErr {
+				t.Errorf("expected %v, got %v", tt.wantErr, err)
+			}
+		})
+	}
+}
+
+func TestEncodedLength(t *testing.T) {
+	params := []*Params{
+		nil,
+		{Time: 1, Memory: 8, Threads: 1, KeyLen: MinKeyLen},
+		{Time: 10, Memory: 1024, Threads: 12, KeyLen: 33},
+		{Time: 1, Memory: 2048, Threads: 1, KeyLen: MaxKeyLen},
+		{Time: 1, Memory: 1024, Threads: 255, KeyLen: 64},
+		{Time: 1, Memory: 1024, Threads: 1, KeyLen: 32, Encoding: EncodingRaw},
+		{Time: 1, Memory: 1024, Threads: 1, KeyLen: MaxKeyLen, Encoding: EncodingBinary},
+	}
+
+	for _, p := range params {
+		hash, err := GenerateFromPassword([]byte("password"), p)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := EncodedLength(p); got != len(hash) {
+			t.Errorf("EncodedLength(%+v) = %d, hash length is %d", p, got, len(hash))
+		}
+	}
+}
+
+func TestMaxEncodedLength(t *testing.T) {
+	tiers := []*Params{
+		nil,
+		{Time: 1, Memory: 1024, Threads: 1, KeyLen: 32},
+		{Time: 100, Memory: MaxMemory, Threads: 255, KeyLen: 16},
+		{Time: 2, Memory: 19 * 1024, Threads: 1, KeyLen: MaxKeyLen},
+		{Time: 2, Memory: 19 * 1024, Threads: 1, KeyLen: MaxKeyLen, Encoding: EncodingBinary},
+	}
+
+	want := 0
+	for _, p := range tiers {
+		want = max(want, EncodedLength(p))
+	}
+	got := MaxEncodedLength(tiers)
+	if got != want {
+		t.Errorf("expected %d, got %d", want, got)
+	}
+	// The MaxKeyLen tier dominates despite smaller numbers
+	if got != EncodedLength(tiers[3]) {
+		t.Errorf("expected the MaxKeyLen tier's length %d, got %d", EncodedLength(tiers[3]), got)
+	}
+
+	hash, err := GenerateFromPassword([]byte("password"), tiers[3])
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(hash) != got {
+		t.Errorf("expected the widest hash to be %d bytes, got %d", got, len(hash))
+	}
+
+	if MaxEncodedLength(nil) != 0 {
+		t.Error("expected 0 for no candidates")
+	}
+}
+
+func TestCustomEncoder(t *testing.T) {
+	// crypt(3)-style alphabet without '+'
+	crypt := base64.NewEncoding("./ABCDEFGHIJKLMNOPQRSTUVWXYZabcdefghijklmnopqrstuvwxyz0123456789").WithPadding(base64.NoPadding)
+
+	params := &Params{Time: 1, Memory: 1024, Threads: 1, KeyLen: 32, Encoder: crypt}
+	h := NewHasher(params)
+
+	for i := 0; i < 10; i++ {
+		hash, err := h.GenerateFromPassword([]byte("password"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if strings.ContainsRune(string(hash[1:]), '+') {
+			t.Errorf("hash %q contains a character outside the custom alphabet", hash)
+		}
+		if len(hash) != EncodedLength(params) {
+			t.Errorf("expected length %d, got %d", EncodedLength(params), len(hash))
+		}
+
+		if err := h.CompareHashAndPassword(hash, []byte("password")); err != nil {
+			t.Errorf("expected hash %q to verify with custom encoder, got %v", hash, err)
+		}
+		if err := h.CompareHashAndPassword(hash, []byte("wrong")); err != ErrMismatchedHashAndPassword {
+			t.Errorf("expected %v, got %v", ErrMismatchedHashAndPassword, err)
+		}
+
+		// The alphabet is recorded in the hash, so decoding needs no
+		// configuration and the decoded params carry an equivalent encoder
+		if err := CompareHashAndPassword(hash, []byte("password")); err != nil {
+			t.Errorf("expected hash %q to verify without configuration, got %v", hash, err)
+		}
+		decoded, err := ExtractParams(hash)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if decoded.Encoder == nil || encoderAlphabet(decoded.Encoder) != encoderAlphabet(crypt) {
+			t.Error("expected decoded params to record the custom alphabet")
+		}
+	}
+}
+
+func TestCustomEncoderValidation(t *testing.T) {
+	const std = "ABCDEFGHIJKLMNOPQRSTUVWXYZabcdefghijklmnopqrstuvwxyz0123456789"
+	for _, enc := range []*base64.Encoding{
+		base64.NewEncoding(std + "$/").WithPadding(base64.NoPadding),
+		base64.NewEncoding(std + ",/").WithPadding(base64.NoPadding),
+		base64.NewEncoding(std + "=/").WithPadding(base64.NoPadding),
+		base64.URLEncoding, // padded
+	} {
+		params := &Params{Time: 1, Memory: 1024, Threads: 1, KeyLen: 32, Encoder: enc}
+		if _, err := GenerateFromPassword([]byte("password"), params); err == nil {
+			t.Errorf("expected Encoder with alphabet %q to be rejected", encoderAlphabet(enc))
+		}
+	}
+
+	// A recorded alphabet that is not 64 distinct characters is invalid
+	hash := "$argon2id$v=19$m=1024,t=1,p=1,alphabet=ABC$MDEyMzQ1Njc4OWFiY2RlZg$k/CHwIHWN/DMFIpEWqAKaG0QDKyrb3t8OfGqTiLnC3E"
+	if _, err := ExtractParams([]byte(hash)); err != ErrInvalidHash {
+		t.Errorf("expected %v, got %v", ErrInvalidHash, err)
+	}
+}
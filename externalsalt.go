@@ -0,0 +1,56 @@
+package argon2id
+
+import "errors"
+
+// ErrEmptyUserSalt is returned when an external salt function is given an
+// empty userSalt.
+var ErrEmptyUserSalt = errors.New("argon2id: empty external user salt")
+
+// GenerateFromPasswordExternalSalt is like GenerateFromPassword but also
+// mixes userSalt, a per-user value kept outside the hash, into the key
+// derivation.
+//
+// The Argon2 salt is the random salt followed by userSalt. Only the random
+// salt is stored in the hash, which is otherwise a standard PHC string, so
+// verifying it requires CompareHashAndPasswordExternalSalt with the same
+// userSalt; other Argon2 implementations cannot verify it without
+// replicating the concatenation.
+//
+// Security model: the random salt still guarantees every hash is unique, so
+// userSalt does not need to be random or unique. Its value lies in being
+// stored apart from the hashes, e.g. in a secrets store: an attacker who
+// obtains only the password database cannot mount an offline guessing
+// attack without also obtaining userSalt. It adds nothing if both are
+// stored together, and losing a user's userSalt makes their hash
+// unverifiable. For a single application-wide secret, prefer a PostHash
+// transform (see DigestTransform).
+func GenerateFromPasswordExternalSalt(password, userSalt []byte, params *Params) ([]byte, error) {
+	if len(userSalt) == 0 {
+		return nil, ErrEmptyUserSalt
+	}
+	hash, _, err := generateFromPassword(password, userSalt, params)
+	return hash, err
+}
+
+// CompareHashAndPasswordExternalSalt verifies a hash generated by
+// GenerateFromPasswordExternalSalt. It behaves like CompareHashAndPassword,
+// including the verify-time limits, and returns
+// ErrMismatchedHashAndPassword if either the password or userSalt is wrong.
+func CompareHashAndPasswordExternalSalt(hashedPassword, password, userSalt []byte) error {
+	if len(userSalt) == 0 {
+		return ErrEmptyUserSalt
+	}
+	_, _, err := verifyHash(hashedPassword, password, verifyOptions{userSalt: userSalt, enforceLimits: true})
+	return err
+}
+
+// mixSalt returns the Argon2 salt for a stored salt and an optional
+// external userSalt.
+func mixSalt(salt, userSalt []byte) []byte {
+	if len(userSalt) == 0 {
+		return salt
+	}
+	mixed := make([]byte, 0, len(salt)+len(userSalt))
+	mixed = append(mixed, salt...)
+	return append(mixed, userSalt...)
+}
@@ -0,0 +1,42 @@
+//go:build !fips && !goexperiment.boringcrypto
+
+package argon2id
+
+import "testing"
+
+func TestExternalSalt(t *testing.T) {
+	params := &Params{Time: 1, Memory: 1024, Threads: 1, KeyLen: 32}
+	userSalt := []byte("per-user secret from the vault")
+
+	hash, err := GenerateFromPasswordExternalSalt([]byte("password"), userSalt, params)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !IsArgon2idHash(hash) {
+		t.Errorf("expected a standard PHC string, got %s", hash)
+	}
+
+	if err := CompareHashAndPasswordExternalSalt(hash, []byte("password"), userSalt); err != nil {
+		t.Errorf("expected round trip to verify, got %v", err)
+	}
+	if err := CompareHashAndPasswordExternalSalt(hash, []byte("wrong"), userSalt); err != ErrMismatchedHashAndPassword {
+		t.Errorf("expected %v for a wrong password, got %v", ErrMismatchedHashAndPassword, err)
+	}
+	if err := CompareHashAndPasswordExternalSalt(hash, []byte("password"), []byte("another user's salt")); err != ErrMismatchedHashAndPassword {
+		t.Errorf("expected %v for a wrong user salt, got %v", ErrMismatchedHashAndPassword, err)
+	}
+
+	// The stored hash alone is not enough
+	if err := CompareHashAndPassword(hash, []byte("password")); err != ErrMismatchedHashAndPassword {
+		t.Errorf("expected %v without the user salt, got %v", ErrMismatchedHashAndPassword, err)
+	}
+}
+
+func TestExternalSaltEmpty(t *testing.T) {
+	if _, err := GenerateFromPasswordExternalSalt([]byte("password"), nil, nil); err != ErrEmptyUserSalt {
+		t.Errorf("expected %v, got %v", ErrEmptyUserSalt, err)
+	}
+	if err := CompareHashAndPasswordExternalSalt([]byte("hash"), []byte("password"), nil); err != ErrEmptyUserSalt {
+		t.Errorf("expected %v, got %v", ErrEmptyUserSalt, err)
+	}
+}
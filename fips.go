@@ -0,0 +1,8 @@
+//go:build fips || goexperiment.boringcrypto
+
+package argon2id
+
+// fipsRestricted is set in FIPS builds (the fips build tag or
+// GOEXPERIMENT=boringcrypto), where Argon2 is not an approved algorithm:
+// hashing and verification return ErrNotFIPSApproved instead of running it.
+const fipsRestricted = true
@@ -0,0 +1,44 @@
+//go:build fips || goexperiment.boringcrypto
+
+package argon2id
+
+import (
+	"testing"
+	"time"
+)
+
+// Run with: go test -tags fips .
+func TestFIPSRestricted(t *testing.T) {
+	params := &Params{Time: 1, Memory: 1024, Threads: 1, KeyLen: 32}
+
+	if _, err := GenerateFromPassword([]byte("password"), params); err != ErrNotFIPSApproved {
+		t.Errorf("GenerateFromPassword: expected ErrNotFIPSApproved, got %v", err)
+	}
+	if _, err := DeriveKey([]byte("password"), []byte("somesalt"), params); err != ErrNotFIPSApproved {
+		t.Errorf("DeriveKey: expected ErrNotFIPSApproved, got %v", err)
+	}
+
+	hash := []byte("$argon2id$v=19$m=1024,t=1,p=1$MDEyMzQ1Njc4OWFiY2RlZg$k/CHwIHWN/DMFIpEWqAKaG0QDKyrb3t8OfGqTiLnC3E")
+	if err := CompareHashAndPassword(hash, []byte("password")); err != ErrNotFIPSApproved {
+		t.Errorf("CompareHashAndPassword: expected ErrNotFIPSApproved, got %v", err)
+	}
+	if _, err := VerifyDetailed(hash, []byte("password"), params); err != ErrNotFIPSApproved {
+		t.Errorf("VerifyDetailed: expected ErrNotFIPSApproved, got %v", err)
+	}
+	if CanVerify(hash) {
+		t.Error("CanVerify: expected false")
+	}
+
+	if _, err := MeasureHashTime(params); err != ErrNotFIPSApproved {
+		t.Errorf("MeasureHashTime: expected ErrNotFIPSApproved, got %v", err)
+	}
+	if _, err := Calibrate(100*time.Millisecond, 1024); err != ErrNotFIPSApproved {
+		t.Errorf("Calibrate: expected ErrNotFIPSApproved, got %v", err)
+	}
+	if _, _, err := RecommendParams(100*time.Millisecond, 4096, 1024); err != ErrNotFIPSApproved {
+		t.Errorf("RecommendParams: expected ErrNotFIPSApproved, got %v", err)
+	}
+	if d := EstimateMigrationTime(100, params, 1); d != 0 {
+		t.Errorf("EstimateMigrationTime: expected 0, got %s", d)
+	}
+}
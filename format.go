@@ -0,0 +1,146 @@
+package argon2id
+
+import (
+	"fmt"
+	"strings"
+)
+
+// HashFormat is the scheme of a stored hash as recognized by DetectFormat.
+type HashFormat uint8
+
+const (
+	// FormatUnknown is anything without a recognized hash prefix.
+	FormatUnknown HashFormat = iota
+
+	// FormatArgon2id is an Argon2id PHC string ("$argon2id$...").
+	FormatArgon2id
+
+	// FormatBcrypt is a bcrypt hash ("$2a$", "$2b$", "$2x$", "$2y$" or "$2$").
+	FormatBcrypt
+
+	// FormatPHC is a PHC or modular crypt string of another scheme, e.g.
+	// "$argon2i$...", "$scrypt$..." or "$6$...".
+	FormatPHC
+)
+
+// String returns the name of the hash format.
+func (f HashFormat) String() string {
+	switch f {
+	case FormatUnknown:
+		return "unknown"
+	case FormatArgon2id:
+		return "argon2id"
+	case FormatBcrypt:
+		return "bcrypt"
+	case FormatPHC:
+		return "phc"
+	default:
+		return fmt.Sprintf("HashFormat(%d)", uint8(f))
+	}
+}
+
+// DetectFormat reports the scheme of hash from its prefix alone, e.g. to
+// route a mixed column to the right verifier. It does not check that the
+// rest of the hash is well formed; use IsArgon2idHash for that. An
+// "argon2id:" label and a trailing line ending are ignored, as when
+// decoding. Compact EncodingRaw and EncodingBinary hashes carry no marker
+// and are FormatUnknown.
+func DetectFormat(hash []byte) HashFormat {
+	s := stripSchemeLabel(trimLineEnding(string(hash)))
+
+	id, ok := phcIdentifier(s)
+	switch {
+	case !ok:
+		return FormatUnknown
+	case id == "argon2id":
+		return FormatArgon2id
+	case id == "2" || (len(id) == 2 && id[0] == '2' && strings.ContainsRune("abxy", rune(id[1]))):
+		return FormatBcrypt
+	default:
+		return FormatPHC
+	}
+}
+
+// phcIdentifier returns the scheme identifier of a "$id$..." or "$id"
+// string (see isPHCIdentifier).
+func phcIdentifier(s string) (string, bool) {
+	rest, ok := strings.CutPrefix(s, "$")
+	if !ok {
+		return "", false
+	}
+	id, _, _ := strings.Cut(rest, "$")
+	return id, isPHCIdentifier(id)
+}
+
+// isPHCIdentifier reports whether id is a valid PHC scheme identifier or
+// parameter name: 1 to 32 characters of [a-z0-9-].
+func isPHCIdentifier(id string) bool {
+	if id == "" || len(id) > 32 {
+		return false
+	}
+	for _, c := range id {
+		if (c < 'a' || c > 'z') && (c < '0' || c > '9') && c != '-' {
+			return false
+		}
+	}
+	return true
+}
+
+// CredentialKind is what a stored credential appears to be; see Classify.
+type CredentialKind uint8
+
+const (
+	// CredentialPlaintext is a value with no known hash prefix, most likely
+	// a password that was stored without hashing.
+	CredentialPlaintext CredentialKind = iota
+
+	// CredentialArgon2id is an Argon2id hash this package can verify.
+	CredentialArgon2id
+
+	// CredentialBcrypt is a bcrypt hash.
+	CredentialBcrypt
+
+	// CredentialOtherHash is a hash of another PHC or modular crypt scheme.
+	CredentialOtherHash
+)
+
+// String returns the name of the credential kind.
+func (k CredentialKind) String() string {
+	switch k {
+	case CredentialPlaintext:
+		return "plaintext"
+	case CredentialArgon2id:
+		return "argon2id"
+	case CredentialBcrypt:
+		return "bcrypt"
+	case CredentialOtherHash:
+		return "other"
+	default:
+		return fmt.Sprintf("CredentialKind(%d)", uint8(k))
+	}
+}
+
+// Classify reports what a stored credential appears to be, e.g. so a login
+// handler serving a mixed column can detect a password that was
+// accidentally stored in plaintext and force a reset.
+//
+// It builds on DetectFormat and is a heuristic: anything without a known
+// hash prefix is CredentialPlaintext, including compact EncodingRaw and
+// EncodingBinary hashes, so do not use it on columns holding those. An
+// "$argon2id$" value that this package cannot verify, e.g. because it is
+// truncated, is CredentialOtherHash rather than plaintext.
+func Classify(credential []byte) CredentialKind {
+	switch DetectFormat(credential) {
+	case FormatArgon2id:
+		if IsArgon2idHash([]byte(trimLineEnding(string(credential)))) {
+			return CredentialArgon2id
+		}
+		return CredentialOtherHash
+	case FormatBcrypt:
+		return CredentialBcrypt
+	case FormatPHC:
+		return CredentialOtherHash
+	default:
+		return CredentialPlaintext
+	}
+}
@@ -0,0 +1,73 @@
+//go:build !fips && !goexperiment.boringcrypto
+
+package argon2id
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestDetectFormat(t *testing.T) {
+	hash, err := GenerateFromPassword([]byte("password"), &Params{Time: 1, Memory: 1024, Threads: 1, KeyLen: 32})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		hash string
+		want HashFormat
+	}{
+		{string(hash), FormatArgon2id},
+		{"ARGON2ID:" + string(hash) + "\r\n", FormatArgon2id},
+		{"$argon2id$v=19$m=65536", FormatArgon2id},
+		{"$2a$10$N9qo8uLOickgx2ZMRZoMyeIjZAgcfl7p92ldGxad68LJZdL17lhWy", FormatBcrypt},
+		{"$2b$12$abc", FormatBcrypt},
+		{"$2y$12$abc", FormatBcrypt},
+		{"$2$05$abc", FormatBcrypt},
+		{"$argon2i$v=19$m=65536,t=1,p=2$mFe3kxhovyEByvwnUtr0ow$nU9AqnoPfzMOQhCHa9BDrQ", FormatPHC},
+		{"$scrypt$ln=16,r=8,p=1$aM15713r3Xsvxbi31lqr1Q$nFNh2CVHVjNldFVKDHDlm4CbdRSCdEBsjjJxD+iCs5E", FormatPHC},
+		{"$6$rounds=5000$salt$hash", FormatPHC},
+		{"$2z$12$abc", FormatPHC},
+		{"", FormatUnknown},
+		{"hunter2", FormatUnknown},
+		{"$", FormatUnknown},
+		{"$$", FormatUnknown},
+		{"$Passw0rd$", FormatUnknown},
+		{"$ecret password", FormatUnknown},
+	}
+	for _, tt := range tests {
+		if got := DetectFormat([]byte(tt.hash)); got != tt.want {
+			t.Errorf("DetectFormat(%q) = %v, want %v", tt.hash, got, tt.want)
+		}
+	}
+}
+
+func TestClassify(t *testing.T) {
+	hash, err := GenerateFromPassword([]byte("password"), &Params{Time: 1, Memory: 1024, Threads: 1, KeyLen: 32})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		credential string
+		want       CredentialKind
+	}{
+		{string(hash), CredentialArgon2id},
+		{string(hash) + "\n", CredentialArgon2id},
+		{string(hash[:bytes.LastIndexByte(hash, '$')]), CredentialOtherHash},
+		{"$2a$10$N9qo8uLOickgx2ZMRZoMyeIjZAgcfl7p92ldGxad68LJZdL17lhWy", CredentialBcrypt},
+		{"$pbkdf2-sha256$29000$N2bMGSPEGCNEiFGqdU7JmQ$3aVpDIHZMmk8pMGBtpJ4Me2R3c2RdvqVprMsrC8uSdk", CredentialOtherHash},
+		{"correct horse battery staple", CredentialPlaintext},
+		{"password", CredentialPlaintext},
+		{"", CredentialPlaintext},
+	}
+	for _, tt := range tests {
+		if got := Classify([]byte(tt.credential)); got != tt.want {
+			t.Errorf("Classify(%q) = %v, want %v", tt.credential, got, tt.want)
+		}
+	}
+
+	if CredentialPlaintext.String() != "plaintext" || FormatBcrypt.String() != "bcrypt" {
+		t.Error("unexpected String() names")
+	}
+}
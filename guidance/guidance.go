@@ -0,0 +1,55 @@
+// Package guidance provides versioned Argon2ID parameter recommendations.
+//
+// Recommendations change as hardware gets faster. Pinning an application to
+// a vintage keeps its cost policy explicit and auditable, and moving to a
+// newer one becomes a deliberate, reviewable change:
+//
+//	hasher := argon2id.NewHasher(guidance.Recommended(2024))
+//
+// Existing hashes can then be upgraded on login with
+// argon2id.Hasher.CompareAndUpgrade.
+//
+// # Vintages
+//
+//	year    memory    time    threads    source
+//	2023    19 MB     2       1          OWASP Password Storage Cheat Sheet
+//	2024    64 MB     3       4          RFC 9106, second recommended option
+//	2025    64 MB     3       4          unchanged from 2024
+//
+// Each vintage is at least as strong as the previous one in both Time and
+// Memory.
+//
+// # Compliance profiles
+//
+// MeetsProfile checks a stored hash against a named standard, e.g.
+// "owasp-2024" or "rfc9106"; Profiles lists the known names.
+package guidance
+
+import "github.com/sixcolors/argon2id"
+
+// vintages lists the recommendations by year, oldest first.
+var vintages = []struct {
+	params argon2id.Params
+	year   int
+}{
+	{year: 2023, params: argon2id.Params{Time: 2, Memory: 19 * 1024, Threads: 1, KeyLen: argon2id.DefaultKeyLen}},
+	{year: 2024, params: argon2id.Params{Time: 3, Memory: 64 * 1024, Threads: 4, KeyLen: argon2id.DefaultKeyLen}},
+	{year: 2025, params: argon2id.Params{Time: 3, Memory: 64 * 1024, Threads: 4, KeyLen: argon2id.DefaultKeyLen}},
+}
+
+// Recommended returns the parameters recommended as of year.
+//
+// A year after the newest vintage returns the newest one, since that
+// guidance still stands. A year before the oldest vintage returns nil. The
+// returned Params are a copy and may be modified.
+func Recommended(year int) *argon2id.Params {
+	var params *argon2id.Params
+	for _, v := range vintages {
+		if v.year > year {
+			break
+		}
+		p := v.params
+		params = &p
+	}
+	return params
+}
@@ -0,0 +1,53 @@
+//go:build !fips && !goexperiment.boringcrypto
+
+package guidance
+
+import (
+	"testing"
+
+	"github.com/sixcolors/argon2id"
+)
+
+func TestRecommendedValidates(t *testing.T) {
+	for _, v := range vintages {
+		params := Recommended(v.year)
+		if params == nil {
+			t.Fatalf("%d: expected params", v.year)
+		}
+		if *params != v.params {
+			t.Errorf("%d: expected %+v, got %+v", v.year, v.params, *params)
+		}
+		if _, err := argon2id.GenerateFromPassword([]byte("password"), params); err != nil {
+			t.Errorf("%d: %v", v.year, err)
+		}
+	}
+}
+
+func TestRecommendedMonotonic(t *testing.T) {
+	for i := 1; i < len(vintages); i++ {
+		prev, cur := vintages[i-1], vintages[i]
+		if cur.year <= prev.year {
+			t.Errorf("vintages out of order: %d after %d", cur.year, prev.year)
+		}
+		if cur.params.Time < prev.params.Time || cur.params.Memory < prev.params.Memory {
+			t.Errorf("%d is weaker than %d", cur.year, prev.year)
+		}
+	}
+}
+
+func TestRecommendedOutOfRange(t *testing.T) {
+	if params := Recommended(vintages[0].year - 1); params != nil {
+		t.Errorf("expected nil before the oldest vintage, got %+v", params)
+	}
+
+	newest := vintages[len(vintages)-1]
+	if params := Recommended(newest.year + 10); params == nil || *params != newest.params {
+		t.Errorf("expected the newest vintage for a future year, got %+v", params)
+	}
+
+	// Callers may modify the result without affecting later calls
+	Recommended(newest.year).Time = 99
+	if Recommended(newest.year).Time == 99 {
+		t.Error("expected Recommended to return a copy")
+	}
+}
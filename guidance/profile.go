@@ -0,0 +1,68 @@
+package guidance
+
+import (
+	"errors"
+	"slices"
+
+	"github.com/sixcolors/argon2id"
+)
+
+// ErrUnknownProfile is returned by MeetsProfile for an unrecognized name.
+var ErrUnknownProfile = errors.New("guidance: unknown profile")
+
+// owaspMinimums are the equivalent-strength configurations listed by the
+// OWASP Password Storage Cheat Sheet; meeting any one of them suffices.
+var owaspMinimums = []argon2id.Params{
+	{Memory: 47104, Time: 1},
+	{Memory: 19456, Time: 2},
+	{Memory: 12288, Time: 3},
+	{Memory: 9216, Time: 4},
+	{Memory: 7168, Time: 5},
+}
+
+// profiles maps compliance profile names to their minimum parameters. A
+// hash meets a profile if it meets any of the listed alternatives.
+var profiles = map[string][]argon2id.Params{
+	"owasp-2023": owaspMinimums,
+	"owasp-2024": owaspMinimums,
+	"rfc9106":    {{Memory: 64 * 1024, Time: 3, KeyLen: 32}}, // Second recommended option (section 4)
+}
+
+// Profiles returns the names accepted by MeetsProfile, sorted.
+func Profiles() []string {
+	names := make([]string, 0, len(profiles))
+	for name := range profiles {
+		names = append(names, name)
+	}
+	slices.Sort(names)
+	return names
+}
+
+// MeetsProfile reports whether the parameters of a stored hash meet or
+// exceed the minimums of the named compliance profile, giving auditors one
+// boolean per record.
+//
+// A hash meets a minimum if its Time and Memory are at least the minimum's,
+// and its digest is at least KeyLen bytes where the profile sets one.
+// Profiles that list several equivalent configurations, like OWASP's, are
+// met by meeting any one of them. ErrUnknownProfile is returned for names
+// not listed by Profiles, and parse errors from argon2id.ExtractParams are
+// returned as is.
+func MeetsProfile(hashedPassword []byte, profile string) (bool, error) {
+	minimums, ok := profiles[profile]
+	if !ok {
+		return false, ErrUnknownProfile
+	}
+
+	params, err := argon2id.ExtractParams(hashedPassword)
+	if err != nil {
+		return false, err
+	}
+
+	for _, minimum := range minimums {
+		if params.Time >= minimum.Time && params.Memory >= minimum.Memory && params.KeyLen >= minimum.KeyLen {
+			return true, nil
+		}
+	}
+	return false, nil
+}
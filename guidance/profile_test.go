@@ -0,0 +1,64 @@
+//go:build !fips && !goexperiment.boringcrypto
+
+package guidance
+
+import (
+	"testing"
+
+	"github.com/sixcolors/argon2id"
+)
+
+func TestMeetsProfile(t *testing.T) {
+	tests := []struct {
+		name    string
+		params  *argon2id.Params
+		profile string
+		want    bool
+	}{
+		{"owasp primary", &argon2id.Params{Time: 2, Memory: 19456, Threads: 1, KeyLen: 32}, "owasp-2024", true},
+		{"owasp alternative", &argon2id.Params{Time: 5, Memory: 7168, Threads: 1, KeyLen: 32}, "owasp-2024", true},
+		{"below owasp", &argon2id.Params{Time: 1, Memory: 19456, Threads: 1, KeyLen: 32}, "owasp-2023", false},
+		{"rfc9106", &argon2id.Params{Time: 3, Memory: 64 * 1024, Threads: 4, KeyLen: 32}, "rfc9106", true},
+		{"rfc9106 short digest", &argon2id.Params{Time: 3, Memory: 64 * 1024, Threads: 4, KeyLen: 16}, "rfc9106", false},
+		{"rfc9106 low memory", &argon2id.Params{Time: 10, Memory: 32 * 1024, Threads: 4, KeyLen: 32}, "rfc9106", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			hash, err := argon2id.GenerateFromPassword([]byte("password"), tt.params)
+			if err != nil {
+				t.Fatal(err)
+			}
+			got, err := MeetsProfile(hash, tt.profile)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got != tt.want {
+				t.Errorf("MeetsProfile(%s) = %v, want %v", tt.profile, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestMeetsProfileErrors(t *testing.T) {
+	hash, err := argon2id.GenerateFromPassword([]byte("password"), Recommended(2023))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := MeetsProfile(hash, "nist-1999"); err != ErrUnknownProfile {
+		t.Errorf("expected %v, got %v", ErrUnknownProfile, err)
+	}
+	if _, err := MeetsProfile([]byte("not a hash"), "owasp-2024"); err == nil {
+		t.Error("expected error for a malformed hash")
+	}
+
+	// Every vintage meets the profile it was taken from
+	if ok, _ := MeetsProfile(hash, "owasp-2023"); !ok {
+		t.Error("expected the 2023 vintage to meet owasp-2023")
+	}
+	for _, name := range Profiles() {
+		if _, err := MeetsProfile(hash, name); err != nil {
+			t.Errorf("%s: %v", name, err)
+		}
+	}
+}
@@ -0,0 +1,171 @@
+package argon2id
+
+import "time"
+
+// Hasher hashes and verifies passwords using a fixed parameter policy.
+//
+// A Hasher lets an application configure its parameters once and pass the
+// policy around, instead of threading a *Params through every call site.
+// A Hasher is safe for concurrent use as long as its fields are not
+// modified after it is first used.
+type Hasher struct {
+	// Params used for new hashes. If nil, DefaultParams() is used.
+	Params *Params
+
+	// Shedder, if set, swaps in weaker params for new hashes under
+	// sustained load. Off by default; see LoadShedder.
+	Shedder *LoadShedder
+
+	// Codec, if set, wraps generated hashes for storage and unwraps stored
+	// values before they are verified. Nil stores plain PHC hashes; see
+	// HashCodec.
+	Codec HashCodec
+
+	// ShadowVerify, if set, is called with the candidate password on every
+	// CompareHashAndPassword, whatever the outcome.
+	//
+	// It supports honeypot accounts: credentials planted to detect credential
+	// stuffing, whose "successful" logins are logged but never grant access.
+	// If the honeypot check only ran for honeypot accounts, or only after a
+	// match, an attacker could tell planted accounts apart by response time.
+	// Doing the side computation here, unconditionally and after the Argon2
+	// comparison, gives every verification the same shape. ShadowVerify
+	// should itself take the same time regardless of its input (e.g. compare
+	// against the honeypot list with crypto/subtle) and must not modify
+	// password.
+	ShadowVerify func(password []byte)
+
+	// MaxCandidateLen, if positive, makes CompareHashAndPassword reject
+	// passwords longer than this many bytes with ErrPasswordTooLong before
+	// any hashing. There is no cryptographic relationship between password
+	// and hash length; this only bounds the work a huge submitted password
+	// can cause. A bound such as 4096 is far above any real password.
+	MaxCandidateLen int
+
+	// KDF, if set, replaces argon2.IDKey when CompareHashAndPassword
+	// recomputes a digest. It exists for interop testing: verifying this
+	// package's hashes with a third-party Argon2id implementation shows
+	// whether both produce identical digests. New hashes are always
+	// generated with argon2.IDKey.
+	KDF KDF
+}
+
+// KDF derives an Argon2id key, with the signature of argon2.IDKey.
+type KDF func(password, salt []byte, time, memory uint32, threads uint8, keyLen uint32) []byte
+
+// NewHasher returns a Hasher using a copy of params.
+//
+// If params is nil, DefaultParams() will be used.
+func NewHasher(params *Params) *Hasher {
+	if params == nil {
+		return &Hasher{Params: DefaultParams()}
+	}
+	return &Hasher{Params: params.Clone()}
+}
+
+// GenerateFromPassword creates an Argon2ID hash of password using the
+// Hasher's parameters. See the package-level GenerateFromPassword.
+//
+// While the Hasher's Shedder is shedding load, its degraded params are used.
+func (h *Hasher) GenerateFromPassword(password []byte) ([]byte, error) {
+	params := h.Params
+	if h.Shedder != nil && h.Shedder.Shedding() {
+		params = h.Shedder.Degraded
+	}
+	hash, err := GenerateFromPassword(password, params)
+	if err != nil {
+		return nil, err
+	}
+	return h.encode(hash)
+}
+
+// CompareHashAndPassword compares a plaintext password with an Argon2ID hash.
+// See the package-level CompareHashAndPassword.
+//
+// Hashes in a compact encoding, which do not record their cost, are verified
+// with the Hasher's parameters. ErrPasswordTooLong is returned for passwords
+// longer than MaxCandidateLen.
+func (h *Hasher) CompareHashAndPassword(hashedPassword, password []byte) error {
+	_, err := h.compare(hashedPassword, password)
+	return err
+}
+
+// compare implements CompareHashAndPassword and returns the stored hash
+// decoded by the Codec.
+func (h *Hasher) compare(hashedPassword, password []byte) ([]byte, error) {
+	hash, _, _, err := h.verify(hashedPassword, password)
+	return hash, err
+}
+
+// verify is the verification sequence shared by compare and VerifyDetailed:
+// it enforces MaxCandidateLen, unwraps the stored value with the Codec,
+// verifies it and calls ShadowVerify. It returns the hash decoded by the
+// Codec and, as verifyHash does, the decoded params and the duration of
+// the key derivation.
+func (h *Hasher) verify(hashedPassword, password []byte) (hash []byte, params *Params, elapsed time.Duration, err error) {
+	if h.MaxCandidateLen > 0 && len(password) > h.MaxCandidateLen {
+		err = ErrPasswordTooLong
+	} else if hash, err = h.decode(hashedPassword); err == nil {
+		params, elapsed, err = verifyHash(hash, password, verifyOptions{fallback: h.Params, kdf: h.KDF, enforceLimits: true})
+	}
+	if h.ShadowVerify != nil {
+		h.ShadowVerify(password)
+	}
+	return hash, params, elapsed, err
+}
+
+// CompareAndUpgrade verifies password against hashedPassword and, if the
+// stored hash is weaker than the Hasher's params (see NeedsRehash), returns
+// a new hash generated with them.
+//
+// newHash is nil when the password does not match (err is then non-nil),
+// when the stored hash already meets the Hasher's params, or while the
+// Hasher's Shedder is shedding load (upgrading to degraded params would be
+// pointless; the next login retries). Callers store newHash when it is
+// non-nil:
+//
+//	newHash, err := hasher.CompareAndUpgrade(user.Hash, password)
+//	if err != nil {
+//	    return err
+//	}
+//	if newHash != nil {
+//	    user.Hash = newHash
+//	}
+func (h *Hasher) CompareAndUpgrade(hashedPassword, password []byte) (newHash []byte, err error) {
+	_, stored, _, err := h.verify(hashedPassword, password)
+	if err != nil {
+		return nil, err
+	}
+
+	params := h.Params
+	if params == nil {
+		params = DefaultParams()
+	}
+	if !weakerThan(stored, params) {
+		return nil, nil
+	}
+	if h.Shedder != nil && h.Shedder.Shedding() {
+		return nil, nil
+	}
+
+	if newHash, err = upgradeHash(password, stored, params); err != nil {
+		return nil, err
+	}
+	return h.encode(newHash)
+}
+
+// encode wraps a generated hash with the Hasher's Codec, if any.
+func (h *Hasher) encode(hash []byte) ([]byte, error) {
+	if h.Codec == nil {
+		return hash, nil
+	}
+	return h.Codec.Encode(hash)
+}
+
+// decode unwraps a stored hash with the Hasher's Codec, if any.
+func (h *Hasher) decode(stored []byte) ([]byte, error) {
+	if h.Codec == nil {
+		return stored, nil
+	}
+	return h.Codec.Decode(stored)
+}
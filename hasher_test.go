@@ -0,0 +1,196 @@
+//go:build !fips && !goexperiment.boringcrypto
+
+package argon2id
+
+import (
+	"testing"
+
+	"golang.org/x/crypto/argon2"
+)
+
+func TestHasher(t *testing.T) {
+	params := &Params{Time: 1, Memory: 1024, Threads: 1, KeyLen: 32}
+	h := NewHasher(params)
+
+	// NewHasher copies its params
+	params.Time = 2
+	if h.Params.Time != 1 {
+		t.Error("expected hasher params to be independent of the caller's params")
+	}
+
+	hash, err := h.GenerateFromPassword([]byte("password"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	extracted, err := ExtractParams(hash)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if extracted.Time != 1 || extracted.Memory != 1024 || extracted.Threads != 1 {
+		t.Errorf("hash not generated with hasher params: %+v", extracted)
+	}
+
+	if err := h.CompareHashAndPassword(hash, []byte("password")); err != nil {
+		t.Error("expected password and hash to match")
+	}
+	if err := h.CompareHashAndPassword(hash, []byte("wrong")); err != ErrMismatchedHashAndPassword {
+		t.Errorf("expected %v, got %v", ErrMismatchedHashAndPassword, err)
+	}
+}
+
+func TestNewHasherDefaults(t *testing.T) {
+	h := NewHasher(nil)
+	if *h.Params != *DefaultParams() {
+		t.Errorf("expected default params, got %+v", h.Params)
+	}
+}
+
+func TestHasherShadowVerify(t *testing.T) {
+	h := NewHasher(&Params{Time: 1, Memory: 1024, Threads: 1, KeyLen: 32})
+
+	var calls []string
+	h.ShadowVerify = func(password []byte) {
+		calls = append(calls, string(password))
+	}
+
+	hash, err := h.GenerateFromPassword([]byte("password"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := h.CompareHashAndPassword(hash, []byte("password")); err != nil {
+		t.Errorf("expected match, got %v", err)
+	}
+	if err := h.CompareHashAndPassword(hash, []byte("wrong")); err != ErrMismatchedHashAndPassword {
+		t.Errorf("expected %v, got %v", ErrMismatchedHashAndPassword, err)
+	}
+	if err := h.CompareHashAndPassword([]byte("malformed"), []byte("other")); err != ErrHashTooShort {
+		t.Errorf("expected %v, got %v", ErrHashTooShort, err)
+	}
+
+	// Runs on every outcome: match, mismatch and decode error
+	want := []string{"password", "wrong", "other"}
+	if len(calls) != len(want) {
+		t.Fatalf("expected %d shadow calls, got %d", len(want), len(calls))
+	}
+	for i := range want {
+		if calls[i] != want[i] {
+			t.Errorf("call %d: expected %q, got %q", i, want[i], calls[i])
+		}
+	}
+}
+
+func TestHasherMaxCandidateLen(t *testing.T) {
+	h := NewHasher(&Params{Time: 1, Memory: 1024, Threads: 1, KeyLen: 32})
+	h.MaxCandidateLen = 4096
+
+	hash, err := h.GenerateFromPassword([]byte("password"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := h.CompareHashAndPassword(hash, []byte("password")); err != nil {
+		t.Errorf("expected match, got %v", err)
+	}
+
+	atLimit := make([]byte, 4096)
+	if err := h.CompareHashAndPassword(hash, atLimit); err != ErrMismatchedHashAndPassword {
+		t.Errorf("expected %v at the limit, got %v", ErrMismatchedHashAndPassword, err)
+	}
+
+	oversized := make([]byte, 1<<20)
+	if err := h.CompareHashAndPassword(hash, oversized); err != ErrPasswordTooLong {
+		t.Errorf("expected %v, got %v", ErrPasswordTooLong, err)
+	}
+
+	// Rejected before decoding, so even a malformed hash reports the length
+	if err := h.CompareHashAndPassword([]byte("malformed"), oversized); err != ErrPasswordTooLong {
+		t.Errorf("expected %v, got %v", ErrPasswordTooLong, err)
+	}
+}
+
+func TestHasherCompareAndUpgrade(t *testing.T) {
+	weak := &Params{Time: 1, Memory: 1024, Threads: 1, KeyLen: 32}
+	h := NewHasher(&Params{Time: 2, Memory: 2048, Threads: 1, KeyLen: 32})
+
+	oldHash, err := GenerateFromPassword([]byte("password"), weak)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Below policy and verified: upgraded
+	newHash, err := h.CompareAndUpgrade(oldHash, []byte("password"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if newHash == nil {
+		t.Fatal("expected an upgraded hash")
+	}
+	if needs, _ := NeedsRehash(newHash, h.Params); needs {
+		t.Error("expected upgraded hash to meet the hasher's params")
+	}
+	if err := h.CompareHashAndPassword(newHash, []byte("password")); err != nil {
+		t.Errorf("expected upgraded hash to verify, got %v", err)
+	}
+
+	// Already at policy: no new hash
+	newHash, err = h.CompareAndUpgrade(newHash, []byte("password"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if newHash != nil {
+		t.Error("expected no upgrade for a hash at policy")
+	}
+
+	// Wrong password: error and no new hash
+	newHash, err = h.CompareAndUpgrade(oldHash, []byte("wrong"))
+	if err != ErrMismatchedHashAndPassword {
+		t.Errorf("expected %v, got %v", ErrMismatchedHashAndPassword, err)
+	}
+	if newHash != nil {
+		t.Error("expected no upgrade for a wrong password")
+	}
+}
+
+func TestHasherKDF(t *testing.T) {
+	params := &Params{Time: 1, Memory: 1024, Threads: 1, KeyLen: 32}
+	hash, err := GenerateFromPassword([]byte("password"), params)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// A stand-in third-party implementation that agrees with argon2.IDKey
+	calls := 0
+	h := NewHasher(params)
+	h.KDF = func(password, salt []byte, time, memory uint32, threads uint8, keyLen uint32) []byte {
+		calls++
+		return argon2.IDKey(password, salt, time, memory, threads, keyLen)
+	}
+	if err := h.CompareHashAndPassword(hash, []byte("password")); err != nil {
+		t.Errorf("expected agreeing KDF to verify, got %v", err)
+	}
+	if err := h.CompareHashAndPassword(hash, []byte("wrong")); err != ErrMismatchedHashAndPassword {
+		t.Errorf("expected %v, got %v", ErrMismatchedHashAndPassword, err)
+	}
+	if calls != 2 {
+		t.Errorf("expected the KDF to be called twice, got %d", calls)
+	}
+
+	// One that disagrees fails even with the right password
+	h.KDF = func(_, _ []byte, _, _ uint32, _ uint8, keyLen uint32) []byte {
+		return make([]byte, keyLen)
+	}
+	if err := h.CompareHashAndPassword(hash, []byte("password")); err != ErrMismatchedHashAndPassword {
+		t.Errorf("expected %v from a disagreeing KDF, got %v", ErrMismatchedHashAndPassword, err)
+	}
+
+	// New hashes are unaffected
+	newHash, err := h.GenerateFromPassword([]byte("password"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := CompareHashAndPassword(newHash, []byte("password")); err != nil {
+		t.Errorf("expected hash generated with argon2.IDKey, got %v", err)
+	}
+}
@@ -0,0 +1,32 @@
+package argon2id
+
+// PruneWeakHistory returns the entries of a password history whose
+// parameters meet minPolicy, dropping the rest.
+//
+// A password history (used to stop users reusing recent passwords) cannot
+// be upgraded like a current hash: its plaintexts are never available
+// again, so old entries stay at whatever cost they were created with.
+// Entries far below the current policy are cheap to attack offline and
+// only marginally useful for reuse checks, so rather than keep enforcing
+// against them indefinitely, drop them once the policy moves on and let the
+// reuse check cover only reasonably strong records.
+//
+// An entry is weak if its Time or Memory is below minPolicy's (see
+// NeedsRehash). Entries that cannot be parsed with ExtractParams are
+// dropped as well. The order of the kept entries is preserved and history
+// itself is not modified. If minPolicy is nil, DefaultParams() will be used.
+func PruneWeakHistory(history [][]byte, minPolicy *Params) [][]byte {
+	if minPolicy == nil {
+		minPolicy = DefaultParams()
+	}
+
+	kept := make([][]byte, 0, len(history))
+	for _, hash := range history {
+		params, err := ExtractParams(hash)
+		if err != nil || weakerThan(params, minPolicy) {
+			continue
+		}
+		kept = append(kept, hash)
+	}
+	return kept
+}
@@ -0,0 +1,35 @@
+//go:build !fips && !goexperiment.boringcrypto
+
+package argon2id
+
+import "testing"
+
+func TestPruneWeakHistory(t *testing.T) {
+	weak := &Params{Time: 1, Memory: 1024, Threads: 1, KeyLen: 32}
+	policy := &Params{Time: 2, Memory: 1024, Threads: 1, KeyLen: 32}
+
+	var history [][]byte
+	for i, params := range []*Params{policy, weak, policy} {
+		hash, err := GenerateFromPassword([]byte{byte('a' + i)}, params)
+		if err != nil {
+			t.Fatal(err)
+		}
+		history = append(history, hash)
+	}
+	history = append(history, []byte("not a hash"))
+
+	kept := PruneWeakHistory(history, policy)
+	if len(kept) != 2 {
+		t.Fatalf("expected 2 entries to be kept, got %d", len(kept))
+	}
+	if string(kept[0]) != string(history[0]) || string(kept[1]) != string(history[2]) {
+		t.Error("expected the strong entries to be kept in order")
+	}
+	if len(history) != 4 {
+		t.Error("expected the input history to be left unmodified")
+	}
+
+	if kept := PruneWeakHistory(history, weak); len(kept) != 3 {
+		t.Errorf("expected all parsable entries to meet the weak policy, got %d", len(kept))
+	}
+}
@@ -0,0 +1,77 @@
+package argon2id
+
+import (
+	"fmt"
+
+	"golang.org/x/crypto/argon2"
+)
+
+// HashInfo describes a hash for logging and auditing.
+type HashInfo struct {
+	Params  *Params // Parameters the hash was generated with
+	Variant string  // Argon2 variant, always "argon2id"
+	Version int     // Argon2 version, e.g. 19
+	SaltLen int     // Salt length in bytes
+}
+
+// String formats the info for logs, e.g. "argon2id v=19 m=65536,t=3,p=2".
+func (i HashInfo) String() string {
+	return fmt.Sprintf("%s v=%d m=%d,t=%d,p=%d", i.Variant, i.Version, i.Params.Memory, i.Params.Time, i.Params.Threads)
+}
+
+// Inspect decodes hashedPassword into a HashInfo without verifying it.
+func Inspect(hashedPassword []byte) (HashInfo, error) {
+	params, salt, _, err := decodeHash(string(hashedPassword), nil)
+	if err != nil {
+		return HashInfo{}, err
+	}
+	return newHashInfo(params, len(salt)), nil
+}
+
+// Describe returns a human-readable description of hashedPassword for
+// admin UIs, e.g. "Argon2id v19, 64 MiB, 3 iterations, 2 lanes, 32-byte
+// key, 16-byte salt". It decodes the hash like Inspect and does not verify
+// it. Compact EncodingRaw and EncodingBinary hashes do not record their
+// costs, so DefaultParams() are reported for them.
+func Describe(hashedPassword []byte) (string, error) {
+	info, err := Inspect(hashedPassword)
+	if err != nil {
+		return "", err
+	}
+	p := info.Params
+	return fmt.Sprintf("Argon2id v%d, %s, %s, %s, %d-byte key, %d-byte salt",
+		info.Version, formatMemory(p.Memory), plural(p.Time, "iteration"),
+		plural(uint32(p.Threads), "lane"), p.KeyLen, info.SaltLen), nil
+}
+
+// formatMemory formats a memory cost in KB, in MiB when it is a whole number.
+func formatMemory(kib uint32) string {
+	if kib >= 1024 && kib%1024 == 0 {
+		return fmt.Sprintf("%d MiB", kib/1024)
+	}
+	return fmt.Sprintf("%d KiB", kib)
+}
+
+// plural formats n followed by noun, adding an "s" unless n is 1.
+func plural(n uint32, noun string) string {
+	if n == 1 {
+		return "1 " + noun
+	}
+	return fmt.Sprintf("%d %ss", n, noun)
+}
+
+// GenerateFromPasswordWithInfo is like GenerateFromPassword but also
+// returns a HashInfo describing the new hash, built from the params used
+// rather than by parsing the hash, so it is cheap enough to log on every
+// registration.
+func GenerateFromPasswordWithInfo(password []byte, params *Params) ([]byte, HashInfo, error) {
+	hash, used, err := GenerateFromPasswordWithUsedParams(password, params)
+	if err != nil {
+		return nil, HashInfo{}, err
+	}
+	return hash, newHashInfo(used, SaltLen), nil
+}
+
+func newHashInfo(params *Params, saltLen int) HashInfo {
+	return HashInfo{Params: params, Variant: "argon2id", Version: argon2.Version, SaltLen: saltLen}
+}
@@ -0,0 +1,99 @@
+//go:build !fips && !goexperiment.boringcrypto
+
+package argon2id
+
+import "testing"
+
+func TestGenerateFromPasswordWithInfo(t *testing.T) {
+	for _, params := range []*Params{
+		nil,
+		{Time: 2, Memory: 2048, Threads: 3, KeyLen: 24},
+		{Time: 1, Memory: 1024, Threads: 1, KeyLen: 32, Encoding: EncodingRaw},
+	} {
+		hash, info, err := GenerateFromPasswordWithInfo([]byte("password"), params)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		fallback := params
+		if fallback == nil {
+			fallback = DefaultParams()
+		}
+		extracted, _, _, err := decodeHash(string(hash), fallback)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !info.Params.Equal(extracted) || info.Params.Encoding != extracted.Encoding {
+			t.Errorf("expected info params %+v to match extracted %+v", info.Params, extracted)
+		}
+		if info.Variant != "argon2id" || info.Version != 19 || info.SaltLen != SaltLen {
+			t.Errorf("unexpected info %+v", info)
+		}
+
+		inspected, err := Inspect(hash)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if params == nil && inspected.String() != info.String() {
+			t.Errorf("expected Inspect to agree, got %s and %s", inspected, info)
+		}
+	}
+
+	_, info, err := GenerateFromPasswordWithInfo([]byte("password"), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := info.String(); got != "argon2id v=19 m=65536,t=3,p=2" {
+		t.Errorf("unexpected String() %q", got)
+	}
+
+	if _, _, err := GenerateFromPasswordWithInfo([]byte("password"), &Params{}); err == nil {
+		t.Error("expected error for invalid params")
+	}
+}
+
+func TestInspect(t *testing.T) {
+	hash, err := GenerateFromPassword([]byte("password"), &Params{Time: 2, Memory: 1024, Threads: 1, KeyLen: 32})
+	if err != nil {
+		t.Fatal(err)
+	}
+	info, err := Inspect(hash)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info.String() != "argon2id v=19 m=1024,t=2,p=1" || info.SaltLen != SaltLen || info.Params.KeyLen != 32 {
+		t.Errorf("unexpected info %+v", info)
+	}
+	if _, err := Inspect([]byte("garbage")); err == nil {
+		t.Error("expected error for an invalid hash")
+	}
+}
+
+func TestDescribe(t *testing.T) {
+	tests := []struct {
+		hash string
+		want string
+	}{
+		{
+			"$argon2id$v=19$m=65536,t=3,p=2$MDEyMzQ1Njc4OWFiY2RlZg$UqcjN8FEhR9C3aVPAePbwhjlawq6mqKGGOFCbIG7i8U",
+			"Argon2id v19, 64 MiB, 3 iterations, 2 lanes, 32-byte key, 16-byte salt",
+		},
+		{
+			"$argon2id$v=19$m=1500,t=1,p=1$MDEyMzQ1Njc4OWFiY2RlZg$UqcjN8FEhR9C3aVPAePbwg",
+			"Argon2id v19, 1500 KiB, 1 iteration, 1 lane, 16-byte key, 16-byte salt",
+		},
+	}
+	for _, tt := range tests {
+		got, err := Describe([]byte(tt.hash))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got != tt.want {
+			t.Errorf("expected %q, got %q", tt.want, got)
+		}
+	}
+
+	if _, err := Describe([]byte("not a hash")); err == nil {
+		t.Error("expected error for a malformed hash")
+	}
+}
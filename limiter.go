@@ -0,0 +1,77 @@
+package argon2id
+
+import (
+	"sync/atomic"
+	"time"
+
+	"golang.org/x/crypto/argon2"
+)
+
+// hashSlots bounds the number of concurrent Argon2 computations. A nil
+// channel means unlimited.
+var hashSlots atomic.Pointer[chan struct{}]
+
+// SetMaxConcurrency limits how many Argon2 computations may run at once
+// across the package, queueing the rest.
+//
+// Each computation allocates Params.Memory, so bounding concurrency bounds
+// peak memory: with the defaults, n concurrent hashes use about n * 64 MB.
+// A value of n <= 0 removes the limit, which is the default. Computations
+// already running when the limit changes are not affected.
+func SetMaxConcurrency(n int) {
+	if n <= 0 {
+		hashSlots.Store(nil)
+		return
+	}
+	slots := make(chan struct{}, n)
+	hashSlots.Store(&slots)
+}
+
+// acquireHashSlot blocks until a computation may start and returns the
+// function that releases its slot
+func acquireHashSlot() func() {
+	slots := hashSlots.Load()
+	if slots == nil {
+		return func() {}
+	}
+	*slots <- struct{}{}
+	return func() { <-*slots }
+}
+
+// hashSlotUsage reports the number of slots in use and the limit, or zeros
+// if concurrency is unlimited
+func hashSlotUsage() (inUse, limit int) {
+	slots := hashSlots.Load()
+	if slots == nil {
+		return 0, 0
+	}
+	return len(*slots), cap(*slots)
+}
+
+// idKey runs argon2.IDKey within the package's concurrency limit
+func idKey(password, salt []byte, params *Params) []byte {
+	key, _ := idKeyTimed(password, salt, params)
+	return key
+}
+
+// idKeyTimed is idKey that also reports how long the computation took,
+// excluding time spent waiting for a slot.
+func idKeyTimed(password, salt []byte, params *Params) ([]byte, time.Duration) {
+	return deriveTimed(argon2.IDKey, password, salt, params)
+}
+
+// deriveTimed is idKeyTimed with kdf in place of argon2.IDKey. The
+// computation runs on the hashing pool if one is set (see SetHashingPool).
+func deriveTimed(kdf KDF, password, salt []byte, params *Params) ([]byte, time.Duration) {
+	release := acquireHashSlot()
+	defer release()
+
+	var key []byte
+	var elapsed time.Duration
+	runHashJob(func() {
+		start := nowFunc()
+		key = kdf(password, salt, params.Time, params.Memory, params.Threads, params.KeyLen)
+		elapsed = nowFunc().Sub(start)
+	})
+	return key, elapsed
+}
@@ -0,0 +1,63 @@
+//go:build !fips && !goexperiment.boringcrypto
+
+package argon2id
+
+import (
+	"testing"
+	"time"
+)
+
+func TestSetMaxConcurrency(t *testing.T) {
+	SetMaxConcurrency(1)
+	t.Cleanup(func() { SetMaxConcurrency(0) })
+
+	// Occupy the only slot so the next computation must queue
+	release := acquireHashSlot()
+
+	done := make(chan error, 1)
+	go func() {
+		_, err := GenerateFromPassword([]byte("password"), &Params{Time: 1, Memory: 1024, Threads: 1, KeyLen: 32})
+		done <- err
+	}()
+
+	select {
+	case <-done:
+		t.Fatal("expected hash to wait for a free slot")
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	release()
+
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Fatal(err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("expected hash to complete once the slot was released")
+	}
+}
+
+func TestHashSlotUsage(t *testing.T) {
+	if inUse, limit := hashSlotUsage(); inUse != 0 || limit != 0 {
+		t.Errorf("expected unlimited by default, got %d/%d", inUse, limit)
+	}
+
+	SetMaxConcurrency(3)
+	t.Cleanup(func() { SetMaxConcurrency(0) })
+
+	release := acquireHashSlot()
+	if inUse, limit := hashSlotUsage(); inUse != 1 || limit != 3 {
+		t.Errorf("expected 1/3, got %d/%d", inUse, limit)
+	}
+	release()
+
+	if inUse, _ := hashSlotUsage(); inUse != 0 {
+		t.Errorf("expected slot to be released, got %d in use", inUse)
+	}
+
+	SetMaxConcurrency(0)
+	if _, limit := hashSlotUsage(); limit != 0 {
+		t.Errorf("expected limit to be removed, got %d", limit)
+	}
+}
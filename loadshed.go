@@ -0,0 +1,83 @@
+package argon2id
+
+import (
+	"errors"
+	"sync"
+	"time"
+)
+
+// LoadShedder temporarily switches a Hasher to weaker parameters while the
+// package's concurrency limit is saturated.
+//
+// Shedding starts once the fraction of busy hashing slots (see
+// SetMaxConcurrency) has stayed at or above Threshold for Window, and stops
+// as soon as it drops below Threshold. It only affects hash generation
+// (registrations, password changes, rehash-on-login); verification cost is
+// fixed by the stored hash. Without a concurrency limit there is no
+// saturation to measure, so nothing is shed.
+//
+// Security/availability tradeoff: hashes created while shedding are cheaper
+// to crack offline. Degraded must therefore be weaker than the Hasher's
+// params in Time or Memory, so NeedsRehash flags those hashes and they are
+// upgraded on the user's next login. Only enable shedding when keeping the
+// service responsive during a spike or attack outweighs briefly weaker
+// hashes, and keep Degraded within your minimum acceptable policy.
+type LoadShedder struct {
+	saturatedSince time.Time
+
+	// Degraded params used while shedding.
+	Degraded *Params
+
+	mu sync.Mutex
+
+	// Window is how long saturation must last before shedding starts.
+	Window time.Duration
+
+	// Threshold is the fraction (0, 1] of busy hashing slots that counts as saturated.
+	Threshold float64
+}
+
+// NewLoadShedder returns a LoadShedder using a copy of degraded.
+//
+// degraded must be valid and weaker than params in Time or Memory so
+// degraded hashes are later flagged by NeedsRehash(hash, params).
+func NewLoadShedder(params, degraded *Params, threshold float64, window time.Duration) (*LoadShedder, error) {
+	if params == nil {
+		params = DefaultParams()
+	}
+	if degraded == nil {
+		return nil, errors.New("argon2id: degraded params are required")
+	}
+	if err := validateParams(degraded); err != nil {
+		return nil, err
+	}
+	if degraded.Time >= params.Time && degraded.Memory >= params.Memory {
+		return nil, errors.New("argon2id: degraded params must be weaker than params in Time or Memory")
+	}
+	if threshold <= 0 || threshold > 1 {
+		return nil, errors.New("argon2id: threshold must be in (0, 1]")
+	}
+
+	return &LoadShedder{Degraded: degraded.Clone(), Threshold: threshold, Window: window}, nil
+}
+
+// Shedding reports whether degraded params are currently in effect,
+// updating the saturation state as a side effect.
+func (l *LoadShedder) Shedding() bool {
+	inUse, limit := hashSlotUsage()
+	saturated := limit > 0 && float64(inUse)/float64(limit) >= l.Threshold
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if !saturated {
+		l.saturatedSince = time.Time{}
+		return false
+	}
+
+	now := nowFunc()
+	if l.saturatedSince.IsZero() {
+		l.saturatedSince = now
+	}
+	return now.Sub(l.saturatedSince) >= l.Window
+}
@@ -0,0 +1,143 @@
+//go:build !fips && !goexperiment.boringcrypto
+
+package argon2id
+
+import (
+	"testing"
+	"time"
+)
+
+func TestNewLoadShedder(t *testing.T) {
+	params := &Params{Time: 3, Memory: 64 * 1024, Threads: 2, KeyLen: 32}
+
+	tests := []struct {
+		name      string
+		degraded  *Params
+		threshold float64
+		wantErr   bool
+	}{
+		{"weaker memory", &Params{Time: 3, Memory: 16 * 1024, Threads: 2, KeyLen: 32}, 0.9, false},
+		{"weaker time", &Params{Time: 1, Memory: 64 * 1024, Threads: 2, KeyLen: 32}, 1, false},
+		{"missing degraded", nil, 0.9, true},
+		{"not weaker", &Params{Time: 3, Memory: 64 * 1024, Threads: 1, KeyLen: 32}, 0.9, true},
+		{"invalid degraded", &Params{Time: 0, Memory: 1024, Threads: 1, KeyLen: 32}, 0.9, true},
+		{"zero threshold", &Params{Time: 1, Memory: 1024, Threads: 1, KeyLen: 32}, 0, true},
+		{"threshold above one", &Params{Time: 1, Memory: 1024, Threads: 1, KeyLen: 32}, 1.5, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := NewLoadShedder(params, tt.degraded, tt.threshold, time.Second)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("NewLoadShedder() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestLoadShedder(t *testing.T) {
+	params := &Params{Time: 2, Memory: 2048, Threads: 1, KeyLen: 32}
+	degraded := &Params{Time: 1, Memory: 1024, Threads: 1, KeyLen: 32}
+
+	shedder, err := NewLoadShedder(params, degraded, 1, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	h := NewHasher(params)
+	h.Shedder = shedder
+
+	// Without a concurrency limit there is nothing to shed
+	if shedder.Shedding() {
+		t.Error("expected no shedding without a concurrency limit")
+	}
+
+	SetMaxConcurrency(2)
+	t.Cleanup(func() { SetMaxConcurrency(0) })
+
+	// One of two slots busy is below the threshold
+	release := acquireHashSlot()
+	hash, err := h.GenerateFromPassword([]byte("password"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if needs, _ := NeedsRehash(hash, params); needs {
+		t.Error("expected normal params below the threshold")
+	}
+
+	// Both slots busy saturates; the hash itself then queues, so inspect the
+	// shedder directly while saturated
+	releaseSecond := acquireHashSlot()
+	if !shedder.Shedding() {
+		t.Error("expected shedding once saturated for the window")
+	}
+	releaseSecond()
+	release()
+
+	if shedder.Shedding() {
+		t.Error("expected shedding to stop below the threshold")
+	}
+}
+
+func TestLoadShedderWindow(t *testing.T) {
+	shedder, err := NewLoadShedder(nil, &Params{Time: 1, Memory: 1024, Threads: 1, KeyLen: 32}, 1, time.Hour)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	SetMaxConcurrency(1)
+	t.Cleanup(func() { SetMaxConcurrency(0) })
+
+	now := time.Unix(0, 0)
+	SetTimeSource(func() time.Time { return now })
+	t.Cleanup(func() { SetTimeSource(nil) })
+
+	release := acquireHashSlot()
+	defer release()
+
+	if shedder.Shedding() {
+		t.Error("expected no shedding before the window elapses")
+	}
+
+	now = now.Add(59 * time.Minute)
+	if shedder.Shedding() {
+		t.Error("expected no shedding before the window elapses")
+	}
+
+	now = now.Add(time.Minute)
+	if !shedder.Shedding() {
+		t.Error("expected shedding once saturated for the window")
+	}
+}
+
+func TestLoadShedderDegradedHashNeedsRehash(t *testing.T) {
+	params := &Params{Time: 2, Memory: 2048, Threads: 1, KeyLen: 32}
+	shedder, err := NewLoadShedder(params, &Params{Time: 1, Memory: 1024, Threads: 1, KeyLen: 32}, 0.5, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	h := NewHasher(params)
+	h.Shedder = shedder
+
+	SetMaxConcurrency(2)
+	t.Cleanup(func() { SetMaxConcurrency(0) })
+
+	// Half the slots busy meets the 0.5 threshold, leaving one slot to hash in
+	release := acquireHashSlot()
+	defer release()
+
+	hash, err := h.GenerateFromPassword([]byte("password"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	needs, err := NeedsRehash(hash, params)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !needs {
+		t.Error("expected degraded hash to be flagged for rehash")
+	}
+	if err := h.CompareHashAndPassword(hash, []byte("password")); err != nil {
+		t.Errorf("expected degraded hash to verify, got %v", err)
+	}
+}
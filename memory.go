@@ -0,0 +1,103 @@
+package argon2id
+
+import (
+	"bytes"
+	"errors"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// ErrInsufficientMemory is returned by CompareHashAndPasswordSafe when
+// verifying a hash would need more memory than is currently available.
+var ErrInsufficientMemory = errors.New("argon2id: not enough available memory to verify hash")
+
+// availableMemory reports the bytes of memory currently available to the
+// process, and false if that cannot be determined. It is a variable so
+// tests can simulate a small instance.
+var availableMemory = systemAvailableMemory
+
+// CompareHashAndPasswordSafe is like CompareHashAndPassword, but first
+// checks the memory the hash needs (see EstimateMemory) against the memory
+// currently available and returns ErrInsufficientMemory instead of
+// computing it if it does not fit. This lets an instance that is smaller
+// than the one that created a hash degrade gracefully, e.g. by retrying on
+// a larger one, instead of being killed for running out of memory.
+//
+// Available memory is read from /proc/meminfo (MemAvailable) and, inside a
+// cgroup v2 container, the remaining room below memory.max. Where neither
+// can be read, e.g. on non-Linux systems, the check is skipped.
+func CompareHashAndPasswordSafe(hashedPassword, password []byte) error {
+	params, salt, hash, err := decodeHashCached(string(hashedPassword), nil)
+	if err != nil {
+		return err
+	}
+	if available, ok := availableMemory(); ok && EstimateMemory(params) > available {
+		return ErrInsufficientMemory
+	}
+	if params.Time > MaxTime || params.Memory > MaxMemory {
+		return ErrHashTooExpensive
+	}
+	_, err = verifyDecoded(params, salt, hash, password, verifyOptions{enforceLimits: true})
+	return err
+}
+
+// systemAvailableMemory returns the smaller of the system's available
+// memory and the room left in the process's cgroup, whichever are known.
+func systemAvailableMemory() (uint64, bool) {
+	available, ok := meminfoAvailable("/proc/meminfo")
+	if room, inCgroup := cgroupRoom("/sys/fs/cgroup"); inCgroup && (!ok || room < available) {
+		available, ok = room, true
+	}
+	return available, ok
+}
+
+// meminfoAvailable returns the MemAvailable value of a /proc/meminfo file
+// in bytes.
+func meminfoAvailable(path string) (uint64, bool) {
+	data, err := os.ReadFile(path) // #nosec G304 - fixed system path
+	if err != nil {
+		return 0, false
+	}
+
+	for line := range strings.Lines(string(data)) {
+		fields := strings.Fields(line)
+		if len(fields) < 2 || fields[0] != "MemAvailable:" {
+			continue
+		}
+		kb, err := strconv.ParseUint(fields[1], 10, 64)
+		if err != nil {
+			return 0, false
+		}
+		return kb * 1024, true
+	}
+	return 0, false
+}
+
+// cgroupRoom returns how many more bytes the cgroup v2 mounted at dir may
+// use before reaching memory.max. It reports false without a limit.
+func cgroupRoom(dir string) (uint64, bool) {
+	limit, ok := readCgroupValue(dir + "/memory.max")
+	if !ok {
+		return 0, false
+	}
+	current, ok := readCgroupValue(dir + "/memory.current")
+	if !ok || current >= limit {
+		return 0, ok
+	}
+	return limit - current, true
+}
+
+// readCgroupValue reads a single number from a cgroup file; "max" means no
+// limit and reports false.
+func readCgroupValue(path string) (uint64, bool) {
+	data, err := os.ReadFile(path) // #nosec G304 - fixed system path
+	if err != nil {
+		return 0, false
+	}
+	v, err := strconv.ParseUint(string(bytes.TrimSpace(data)), 10, 64)
+	if err != nil {
+		return 0, false
+	}
+	return v, true
+}
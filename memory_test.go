@@ -0,0 +1,76 @@
+//go:build !fips && !goexperiment.boringcrypto
+
+package argon2id
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestCompareHashAndPasswordSafe(t *testing.T) {
+	params := &Params{Time: 1, Memory: 4096, Threads: 1, KeyLen: 32}
+	hash, err := GenerateFromPassword([]byte("password"), params)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	saved := availableMemory
+	t.Cleanup(func() { availableMemory = saved })
+
+	availableMemory = func() (uint64, bool) { return EstimateMemory(params) - 1, true }
+	if err := CompareHashAndPasswordSafe(hash, []byte("password")); err != ErrInsufficientMemory {
+		t.Errorf("expected ErrInsufficientMemory, got %v", err)
+	}
+
+	availableMemory = func() (uint64, bool) { return EstimateMemory(params), true }
+	if err := CompareHashAndPasswordSafe(hash, []byte("password")); err != nil {
+		t.Errorf("expected match, got %v", err)
+	}
+	if err := CompareHashAndPasswordSafe(hash, []byte("wrong")); err != ErrMismatchedHashAndPassword {
+		t.Errorf("expected ErrMismatchedHashAndPassword, got %v", err)
+	}
+
+	// Unknown available memory skips the check
+	availableMemory = func() (uint64, bool) { return 0, false }
+	if err := CompareHashAndPasswordSafe(hash, []byte("password")); err != nil {
+		t.Errorf("expected match, got %v", err)
+	}
+
+	if err := CompareHashAndPasswordSafe([]byte("invalid"), []byte("password")); err == nil {
+		t.Error("expected error for invalid hash")
+	}
+}
+
+func TestSystemAvailableMemorySources(t *testing.T) {
+	dir := t.TempDir()
+	write := func(name, content string) string {
+		path := filepath.Join(dir, name)
+		if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+			t.Fatal(err)
+		}
+		return path
+	}
+
+	meminfo := write("meminfo", "MemTotal:       16384000 kB\nMemFree:         1000000 kB\nMemAvailable:    8192000 kB\n")
+	if got, ok := meminfoAvailable(meminfo); !ok || got != 8192000*1024 {
+		t.Errorf("expected %d, got %d (%v)", 8192000*1024, got, ok)
+	}
+	if _, ok := meminfoAvailable(write("old", "MemTotal: 1 kB\n")); ok {
+		t.Error("expected no value without MemAvailable")
+	}
+
+	write("memory.max", "max\n")
+	write("memory.current", "1000\n")
+	if _, ok := cgroupRoom(dir); ok {
+		t.Error("expected no room without a cgroup limit")
+	}
+	write("memory.max", "5000\n")
+	if got, ok := cgroupRoom(dir); !ok || got != 4000 {
+		t.Errorf("expected 4000, got %d (%v)", got, ok)
+	}
+	write("memory.current", "6000\n")
+	if got, ok := cgroupRoom(dir); !ok || got != 0 {
+		t.Errorf("expected 0 at the limit, got %d (%v)", got, ok)
+	}
+}
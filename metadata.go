@@ -0,0 +1,131 @@
+package argon2id
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/binary"
+	"errors"
+	"strings"
+	"time"
+)
+
+// MetadataScheme is the Scheme recorded by GenerateWithMetadata.
+const MetadataScheme = "argon2id"
+
+// ErrInvalidMetadata is returned when unmarshaling malformed Metadata.
+var ErrInvalidMetadata = errors.New("argon2id: invalid metadata")
+
+// ErrMetadataSignature is returned by VerifyMetadata when signed metadata
+// was not produced by Sign with the same key and hash.
+var ErrMetadataSignature = errors.New("argon2id: metadata signature mismatch")
+
+// Metadata describes a hash without being part of it.
+//
+// The PHC string has no room for extra fields that other implementations
+// would accept, so metadata is kept as a sidecar value that the caller
+// stores in its own column next to the hash. MarshalText output is not
+// authenticated; use Sign and VerifyMetadata when whoever can write the
+// column must not be able to change the creation time.
+type Metadata struct {
+	Created time.Time // When the hash was generated (UTC)
+	Scheme  string    // What produced the hash, e.g. MetadataScheme
+}
+
+// GenerateWithMetadata is like GenerateFromPassword but also returns
+// Metadata recording when the hash was created, for age-based rotation with
+// NeedsRehashByAge.
+func GenerateWithMetadata(password []byte, params *Params) (hash []byte, meta Metadata, err error) {
+	hash, err = GenerateFromPassword(password, params)
+	if err != nil {
+		return nil, Metadata{}, err
+	}
+	return hash, Metadata{Created: nowFunc().UTC(), Scheme: MetadataScheme}, nil
+}
+
+// NeedsRehashByAge reports whether a hash created as described by meta is
+// older than maxAge. Metadata without a creation time is treated as
+// expired, so hashes of unknown age are rotated.
+func NeedsRehashByAge(meta Metadata, maxAge time.Duration) bool {
+	if meta.Created.IsZero() {
+		return true
+	}
+	return nowFunc().Sub(meta.Created) > maxAge
+}
+
+// MarshalText encodes m as "scheme,created" with the creation time in
+// RFC 3339 format, e.g. "argon2id,2024-05-01T12:00:00Z". It also makes
+// Metadata marshal as a JSON string.
+func (m Metadata) MarshalText() ([]byte, error) {
+	if strings.ContainsRune(m.Scheme, ',') {
+		return nil, ErrInvalidMetadata
+	}
+	created, err := m.Created.UTC().MarshalText()
+	if err != nil {
+		return nil, err
+	}
+	return []byte(m.Scheme + "," + string(created)), nil
+}
+
+// UnmarshalText decodes the format written by MarshalText.
+func (m *Metadata) UnmarshalText(text []byte) error {
+	scheme, created, found := strings.Cut(string(text), ",")
+	if !found || scheme == "" {
+		return ErrInvalidMetadata
+	}
+	var t time.Time
+	if err := t.UnmarshalText([]byte(created)); err != nil {
+		return ErrInvalidMetadata
+	}
+	m.Scheme = scheme
+	m.Created = t
+	return nil
+}
+
+// Sign encodes m like MarshalText and appends an HMAC-SHA256 tag, keyed by
+// key, over the encoding and hash, e.g. "argon2id,2024-05-01T12:00:00Z,tag".
+// Binding the tag to hash stops metadata from being moved to another row.
+// The key must not be empty; keep it outside the database.
+func (m Metadata) Sign(hash, key []byte) ([]byte, error) {
+	if len(key) == 0 {
+		return nil, errors.New("argon2id: metadata key must not be empty")
+	}
+	text, err := m.MarshalText()
+	if err != nil {
+		return nil, err
+	}
+	tag := base64.RawStdEncoding.EncodeToString(metadataMAC(text, hash, key))
+	return append(append(text, ','), tag...), nil
+}
+
+// VerifyMetadata decodes metadata written by Sign, returning
+// ErrMetadataSignature unless its tag matches hash and key.
+func VerifyMetadata(signed, hash, key []byte) (Metadata, error) {
+	i := strings.LastIndexByte(string(signed), ',')
+	if i < 0 {
+		return Metadata{}, ErrInvalidMetadata
+	}
+	text := signed[:i]
+	tag, err := base64.RawStdEncoding.DecodeString(string(signed[i+1:]))
+	if err != nil {
+		return Metadata{}, ErrInvalidMetadata
+	}
+	if len(key) == 0 || !hmac.Equal(tag, metadataMAC(text, hash, key)) {
+		return Metadata{}, ErrMetadataSignature
+	}
+	var m Metadata
+	if err := m.UnmarshalText(text); err != nil {
+		return Metadata{}, err
+	}
+	return m, nil
+}
+
+// metadataMAC returns HMAC-SHA256(key, len(text) || text || hash), with the
+// length as 8 big-endian bytes so text and hash cannot trade bytes.
+func metadataMAC(text, hash, key []byte) []byte {
+	mac := hmac.New(sha256.New, key)
+	mac.Write(binary.BigEndian.AppendUint64(nil, uint64(len(text))))
+	mac.Write(text)
+	mac.Write(hash)
+	return mac.Sum(nil)
+}
@@ -0,0 +1,120 @@
+//go:build !fips && !goexperiment.boringcrypto
+
+package argon2id
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestGenerateWithMetadata(t *testing.T) {
+	created := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	now := created
+	SetTimeSource(func() time.Time { return now })
+	t.Cleanup(func() { SetTimeSource(nil) })
+
+	hash, meta, err := GenerateWithMetadata([]byte("password"), &Params{Time: 1, Memory: 1024, Threads: 1, KeyLen: 32})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := CompareHashAndPassword(hash, []byte("password")); err != nil {
+		t.Errorf("expected hash to verify, got %v", err)
+	}
+	if !meta.Created.Equal(created) || meta.Scheme != MetadataScheme {
+		t.Errorf("unexpected metadata %+v", meta)
+	}
+
+	now = created.Add(89 * 24 * time.Hour)
+	if NeedsRehashByAge(meta, 90*24*time.Hour) {
+		t.Error("expected no rehash before maxAge")
+	}
+	now = created.Add(91 * 24 * time.Hour)
+	if !NeedsRehashByAge(meta, 90*24*time.Hour) {
+		t.Error("expected rehash after maxAge")
+	}
+	if !NeedsRehashByAge(Metadata{}, 90*24*time.Hour) {
+		t.Error("expected rehash for unknown age")
+	}
+}
+
+func TestMetadataMarshal(t *testing.T) {
+	meta := Metadata{Created: time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC), Scheme: MetadataScheme}
+
+	text, err := meta.MarshalText()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(text) != "argon2id,2024-05-01T12:00:00Z" {
+		t.Errorf("unexpected encoding %q", text)
+	}
+
+	var decoded Metadata
+	if err := decoded.UnmarshalText(text); err != nil {
+		t.Fatal(err)
+	}
+	if decoded != meta {
+		t.Errorf("expected %+v, got %+v", meta, decoded)
+	}
+
+	data, err := json.Marshal(meta)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(data) != `"argon2id,2024-05-01T12:00:00Z"` {
+		t.Errorf("unexpected JSON %s", data)
+	}
+
+	for _, bad := range []string{"", "argon2id", ",2024-05-01T12:00:00Z", "argon2id,yesterday"} {
+		if err := decoded.UnmarshalText([]byte(bad)); err != ErrInvalidMetadata {
+			t.Errorf("expected %v for %q, got %v", ErrInvalidMetadata, bad, err)
+		}
+	}
+	if _, err := (Metadata{Scheme: "a,b"}).MarshalText(); err != ErrInvalidMetadata {
+		t.Errorf("expected %v for a scheme containing a comma, got %v", ErrInvalidMetadata, err)
+	}
+}
+
+func TestMetadataSign(t *testing.T) {
+	meta := Metadata{Created: time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC), Scheme: MetadataScheme}
+	hash := []byte("$argon2id$v=19$m=1024,t=1,p=1$c2FsdA$aGFzaA")
+	key := []byte("metadata key")
+
+	signed, err := meta.Sign(hash, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasPrefix(string(signed), "argon2id,2024-05-01T12:00:00Z,") {
+		t.Errorf("unexpected encoding %q", signed)
+	}
+	decoded, err := VerifyMetadata(signed, hash, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if decoded != meta {
+		t.Errorf("expected %+v, got %+v", meta, decoded)
+	}
+
+	tampered := strings.Replace(string(signed), "2024-05-01", "2030-05-01", 1)
+	if _, err := VerifyMetadata([]byte(tampered), hash, key); err != ErrMetadataSignature {
+		t.Errorf("expected %v for tampered metadata, got %v", ErrMetadataSignature, err)
+	}
+	if _, err := VerifyMetadata(signed, []byte("$argon2id$v=19$m=1024,t=1,p=1$c2FsdA$b3RoZXI"), key); err != ErrMetadataSignature {
+		t.Errorf("expected %v for another hash, got %v", ErrMetadataSignature, err)
+	}
+	if _, err := VerifyMetadata(signed, hash, []byte("other key")); err != ErrMetadataSignature {
+		t.Errorf("expected %v for another key, got %v", ErrMetadataSignature, err)
+	}
+	if _, err := VerifyMetadata(signed, hash, nil); err != ErrMetadataSignature {
+		t.Errorf("expected %v for an empty key, got %v", ErrMetadataSignature, err)
+	}
+	for _, bad := range []string{"", "argon2id,2024-05-01T12:00:00Z,!!"} {
+		if _, err := VerifyMetadata([]byte(bad), hash, key); err != ErrInvalidMetadata {
+			t.Errorf("expected %v for %q, got %v", ErrInvalidMetadata, bad, err)
+		}
+	}
+	if _, err := meta.Sign(hash, nil); err == nil {
+		t.Error("expected an error for an empty key")
+	}
+}
@@ -0,0 +1,179 @@
+package argon2id
+
+import (
+	"fmt"
+	"time"
+)
+
+// Report counts the outcomes of a MigrateStream run.
+type Report struct {
+	Scanned    int // Hashes read from the cursor
+	Current    int // Already at or above the desired params
+	Migrated   int // Verified, rehashed and committed
+	Deferred   int // Need rehashing but no plaintext was available yet
+	Mismatched int // The supplied plaintext did not match the stored hash
+	Invalid    int // Could not be decoded
+}
+
+// MigrateStream rehashes stored hashes to desired params while paging
+// through a database cursor.
+//
+// next returns the users one at a time and ok = false once exhausted, so
+// the whole table never has to be held in memory. For every hash that needs
+// rehashing (see NeedsRehash), onVerifyNeeded is asked for the user's
+// plaintext password. Plaintext is normally only available while the user
+// is logging in, so onVerifyNeeded usually reports hasPassword = false and
+// the user is counted as Deferred: run the stream again later, or upgrade
+// at login with Hasher.CompareAndUpgrade. When a password is supplied it is
+// verified against the stored hash first, and only a matching password is
+// rehashed and handed to commit. A nil onVerifyNeeded defers every user.
+//
+// Stored hashes are treated as trusted (see CompareHashAndPasswordTrusted).
+// Hashes that cannot be decoded and passwords that do not match are counted
+// rather than aborting the run. An error from hashing or commit stops the
+// run and is returned along with the counts so far. If desired is nil,
+// DefaultParams() will be used.
+func MigrateStream(
+	next func() (id string, hash []byte, ok bool),
+	onVerifyNeeded func(id string) (password []byte, hasPassword bool),
+	desired *Params,
+	commit func(id string, newHash []byte) error,
+) (Report, error) {
+	var report Report
+	if desired == nil {
+		desired = DefaultParams()
+	}
+	if err := validateParams(desired); err != nil {
+		return report, err
+	}
+
+	for {
+		id, hash, ok := next()
+		if !ok {
+			return report, nil
+		}
+		report.Scanned++
+		if err := migrateOne(&report, id, hash, onVerifyNeeded, desired, commit); err != nil {
+			return report, fmt.Errorf("argon2id: migrate %s: %w", id, err)
+		}
+	}
+}
+
+// EstimateMigrationTime projects how long rehashing userCount users to
+// params takes with concurrency hashes in flight at once, e.g. to plan a
+// maintenance window for a bulk MigrateStream run.
+//
+// It measures one hash with MeasureHashTime and multiplies by the number of
+// rounds of concurrency hashes needed. Only generating the new hashes is
+// counted: verifying each old hash first costs another hash at the old
+// params, so add EstimateMigrationTime(userCount, oldParams, concurrency)
+// when passwords are verified before rehashing. The projection assumes
+// concurrent hashes do not slow each other down, which only holds while
+// concurrency * params.Threads fits the available cores and concurrency *
+// EstimateMemory(params) fits in memory.
+//
+// If params is nil, DefaultParams() will be used. A concurrency below 1
+// counts as 1. It returns 0 if userCount is not positive or params are
+// invalid.
+func EstimateMigrationTime(userCount int, params *Params, concurrency int) time.Duration {
+	return estimateMigrationTime(userCount, params, concurrency, MeasureHashTime)
+}
+
+// estimateMigrationTime is EstimateMigrationTime with a pluggable measurement.
+func estimateMigrationTime(userCount int, params *Params, concurrency int, measure func(*Params) (time.Duration, error)) time.Duration {
+	if userCount <= 0 {
+		return 0
+	}
+	perHash, err := measure(params)
+	if err != nil {
+		return 0
+	}
+	concurrency = max(concurrency, 1)
+	rounds := (userCount + concurrency - 1) / concurrency
+	return time.Duration(rounds) * perHash
+}
+
+// migrateOne processes a single user for MigrateStream, recording the
+// outcome in report. Only hashing and commit errors are returned.
+func migrateOne(
+	report *Report,
+	id string,
+	hash []byte,
+	onVerifyNeeded func(id string) (password []byte, hasPassword bool),
+	desired *Params,
+	commit func(id string, newHash []byte) error,
+) error {
+	stored, err := ExtractParams(hash)
+	switch {
+	case err != nil:
+		report.Invalid++
+		return nil
+	case !weakerThan(stored, desired):
+		report.Current++
+		return nil
+	}
+
+	var password []byte
+	hasPassword := false
+	if onVerifyNeeded != nil {
+		password, hasPassword = onVerifyNeeded(id)
+	}
+	if !hasPassword {
+		report.Deferred++
+		return nil
+	}
+
+	if err := CompareHashAndPasswordTrusted(hash, password); err != nil {
+		report.Mismatched++
+		return nil
+	}
+
+	newHash, err := upgradeHash(password, stored, desired)
+	if err != nil {
+		return err
+	}
+	if err := commit(id, newHash); err != nil {
+		return err
+	}
+	report.Migrated++
+	return nil
+}
+
+// ParamsFromBcryptCost maps a bcrypt cost onto argon2id params of roughly
+// equivalent strength, so a user migrated from bcrypt gets a hash at least
+// as expensive to attack as their old one.
+//
+// The mapping is approximate. Each bcrypt cost step doubles the work, and
+// bcrypt cost 10 (its default) takes about as long on typical server
+// hardware as DefaultParams, so cost 10 maps to DefaultParams. Each step
+// above or below then doubles or halves Memory, which is what makes
+// Argon2 expensive on attacker hardware, until MaxMemory is reached at cost
+// 14; further steps double Time up to MaxTime at cost 20 and above. Costs
+// below 4 or above 31 (bcrypt's limits) are clamped. The bcryptcompat
+// package documents the full table.
+func ParamsFromBcryptCost(cost int) *Params {
+	const (
+		minCost     = 4
+		maxCost     = 31
+		defaultCost = 10
+	)
+	cost = max(min(cost, maxCost), minCost)
+	params := DefaultParams()
+
+	// Memory doubles per step until it reaches MaxMemory
+	memory := uint64(params.Memory)
+	for c := defaultCost; c < cost && memory < MaxMemory; c++ {
+		memory *= 2
+	}
+	for c := defaultCost; c > cost; c-- {
+		memory /= 2
+	}
+	params.Memory = uint32(min(memory, MaxMemory)) // #nosec G115 - bounded by MaxMemory
+
+	// Then time doubles per remaining step until it reaches MaxTime
+	for c := defaultCost + 4; c < cost && params.Time < MaxTime; c++ {
+		params.Time = min(params.Time*2, MaxTime)
+	}
+
+	return params
+}
@@ -0,0 +1,174 @@
+//go:build !fips && !goexperiment.boringcrypto
+
+package argon2id
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+// cursor returns a MigrateStream next func over ids in order.
+func cursor(ids []string, hashes map[string][]byte) func() (string, []byte, bool) {
+	i := 0
+	return func() (string, []byte, bool) {
+		if i == len(ids) {
+			return "", nil, false
+		}
+		id := ids[i]
+		i++
+		return id, hashes[id], true
+	}
+}
+
+func TestMigrateStream(t *testing.T) {
+	weak := &Params{Time: 1, Memory: 1024, Threads: 1, KeyLen: 32}
+	desired := &Params{Time: 2, Memory: 1024, Threads: 1, KeyLen: 32}
+
+	hashes := map[string][]byte{"invalid": []byte("not a hash")}
+	for id, params := range map[string]*Params{"current": desired, "login": weak, "offline": weak, "wrong": weak} {
+		hash, err := GenerateFromPassword([]byte(id+"-password"), params)
+		if err != nil {
+			t.Fatal(err)
+		}
+		hashes[id] = hash
+	}
+
+	// Plaintext is only known for users logging in right now
+	passwords := map[string][]byte{"login": []byte("login-password"), "wrong": []byte("typo")}
+	onVerifyNeeded := func(id string) ([]byte, bool) {
+		password, ok := passwords[id]
+		return password, ok
+	}
+	committed := map[string][]byte{}
+	commit := func(id string, newHash []byte) error {
+		committed[id] = newHash
+		return nil
+	}
+
+	rehashed := 0
+	OnRehash = func(_, _ *Params) { rehashed++ }
+	t.Cleanup(func() { OnRehash = nil })
+
+	ids := []string{"current", "login", "offline", "wrong", "invalid"}
+	report, err := MigrateStream(cursor(ids, hashes), onVerifyNeeded, desired, commit)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if rehashed != 1 {
+		t.Errorf("expected OnRehash to fire once, got %d", rehashed)
+	}
+
+	want := Report{Scanned: 5, Current: 1, Migrated: 1, Deferred: 1, Mismatched: 1, Invalid: 1}
+	if report != want {
+		t.Errorf("expected %+v, got %+v", want, report)
+	}
+	if len(committed) != 1 || committed["login"] == nil {
+		t.Fatalf("expected only login to be committed, got %v", committed)
+	}
+	if needs, _ := NeedsRehash(committed["login"], desired); needs {
+		t.Error("expected the committed hash to meet the desired params")
+	}
+	if err := CompareHashAndPassword(committed["login"], []byte("login-password")); err != nil {
+		t.Errorf("expected the committed hash to verify, got %v", err)
+	}
+}
+
+func TestMigrateStreamCommitError(t *testing.T) {
+	weak := &Params{Time: 1, Memory: 1024, Threads: 1, KeyLen: 32}
+	hash, err := GenerateFromPassword([]byte("password"), weak)
+	if err != nil {
+		t.Fatal(err)
+	}
+	hashes := map[string][]byte{"a": hash, "b": hash}
+
+	errStore := errors.New("store unavailable")
+	report, err := MigrateStream(
+		cursor([]string{"a", "b"}, hashes),
+		func(string) ([]byte, bool) { return []byte("password"), true },
+		&Params{Time: 2, Memory: 1024, Threads: 1, KeyLen: 32},
+		func(string, []byte) error { return errStore },
+	)
+	if !errors.Is(err, errStore) {
+		t.Errorf("expected %v, got %v", errStore, err)
+	}
+	if report.Scanned != 1 || report.Migrated != 0 {
+		t.Errorf("expected the run to stop at the first user, got %+v", report)
+	}
+
+	// Without a password source every outdated user is deferred
+	report, err = MigrateStream(cursor([]string{"a", "b"}, hashes), nil, &Params{Time: 2, Memory: 1024, Threads: 1, KeyLen: 32}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if report.Deferred != 2 {
+		t.Errorf("expected 2 deferred, got %+v", report)
+	}
+}
+
+func TestEstimateMigrationTime(t *testing.T) {
+	measure := func(*Params) (time.Duration, error) { return 100 * time.Millisecond, nil }
+
+	base := estimateMigrationTime(1000, nil, 1, measure)
+	if base != 100*time.Second {
+		t.Fatalf("expected 100s, got %s", base)
+	}
+	for _, n := range []int{2, 10, 100} {
+		if got := estimateMigrationTime(1000*n, nil, 1, measure); got != base*time.Duration(n) {
+			t.Errorf("%dx users: expected %s, got %s", n, base*time.Duration(n), got)
+		}
+		if got := estimateMigrationTime(1000, nil, n, measure); got != base/time.Duration(n) {
+			t.Errorf("concurrency %d: expected %s, got %s", n, base/time.Duration(n), got)
+		}
+	}
+
+	// A partial last round still takes a full hash
+	if got := estimateMigrationTime(5, nil, 4, measure); got != 200*time.Millisecond {
+		t.Errorf("expected 200ms, got %s", got)
+	}
+	if got := estimateMigrationTime(10, nil, 0, measure); got != time.Second {
+		t.Errorf("expected concurrency 0 to count as 1, got %s", got)
+	}
+	if got := estimateMigrationTime(0, nil, 1, measure); got != 0 {
+		t.Errorf("expected 0 for no users, got %s", got)
+	}
+	failing := func(*Params) (time.Duration, error) { return 0, errors.New("invalid") }
+	if got := estimateMigrationTime(10, nil, 1, failing); got != 0 {
+		t.Errorf("expected 0 when measuring fails, got %s", got)
+	}
+
+	params := &Params{Time: 1, Memory: 1024, Threads: 1, KeyLen: 32}
+	if got := EstimateMigrationTime(100, params, 4); got <= 0 {
+		t.Errorf("expected a positive estimate, got %s", got)
+	}
+	if got := EstimateMigrationTime(100, &Params{}, 4); got != 0 {
+		t.Errorf("expected 0 for invalid params, got %s", got)
+	}
+}
+
+func TestParamsFromBcryptCost(t *testing.T) {
+	if *ParamsFromBcryptCost(10) != *DefaultParams() {
+		t.Errorf("expected bcrypt's default cost to map to DefaultParams, got %+v", ParamsFromBcryptCost(10))
+	}
+	if p := ParamsFromBcryptCost(4); p.Memory != 1024 || p.Time != DefaultTime {
+		t.Errorf("unexpected params for cost 4: %+v", p)
+	}
+	if p := ParamsFromBcryptCost(31); p.Memory != MaxMemory || p.Time != MaxTime {
+		t.Errorf("unexpected params for cost 31: %+v", p)
+	}
+	if *ParamsFromBcryptCost(0) != *ParamsFromBcryptCost(4) || *ParamsFromBcryptCost(50) != *ParamsFromBcryptCost(31) {
+		t.Error("expected out-of-range costs to be clamped")
+	}
+
+	prev := ParamsFromBcryptCost(4)
+	for cost := 4; cost <= 31; cost++ {
+		params := ParamsFromBcryptCost(cost)
+		if err := validateParams(params); err != nil {
+			t.Errorf("cost %d: %v", cost, err)
+		}
+		if params.Time < prev.Time || params.Memory < prev.Memory {
+			t.Errorf("cost %d: %+v is weaker than cost %d", cost, params, cost-1)
+		}
+		prev = params
+	}
+}
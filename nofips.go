@@ -0,0 +1,6 @@
+//go:build !fips && !goexperiment.boringcrypto
+
+package argon2id
+
+// fipsRestricted is false outside FIPS builds; see fips.go.
+const fipsRestricted = false
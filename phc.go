@@ -0,0 +1,141 @@
+package argon2id
+
+import (
+	"encoding/base64"
+	"strconv"
+	"strings"
+)
+
+// ParsePHC parses a string in the PHC string format of any scheme:
+//
+//	$<id>[$v=<version>][$<param>=<value>(,<param>=<value>)*][$<salt>[$<hash>]]
+//
+// It returns the scheme identifier, the version (0 if there is no "v="
+// segment), the parameters as raw strings, and the salt and digest decoded
+// from unpadded standard base64 (nil if absent). It checks only the
+// generic structure: identifiers and parameter names of 1 to 32 characters
+// of [a-z0-9-], parameters of the form name=value without duplicates and
+// well-formed base64. Anything scheme-specific, such as which parameters
+// are required, is up to the caller. Malformed strings return
+// ErrInvalidHash.
+//
+// It is meant for inspecting hashes of other schemes stored alongside
+// Argon2id ones, e.g. "$scrypt$ln=16,r=8,p=1$...". Argon2id hashes are
+// split the same way when decoding, with the Argon2id validation and
+// additional tolerances (see CompareHashAndPassword) layered on top; use
+// ParseHash for those.
+func ParsePHC(s string) (scheme string, version int, params map[string]string, salt, digest []byte, err error) {
+	phc, ok := splitPHCString(s)
+	if !ok || !isPHCIdentifier(phc.id) {
+		return "", 0, nil, nil, nil, ErrInvalidHash
+	}
+	if phc.version != "" {
+		if version, ok = parsePHCVersion(phc.version); !ok {
+			return "", 0, nil, nil, nil, ErrInvalidHash
+		}
+	}
+	if phc.params != "" {
+		if params, ok = parsePHCParams(phc.params); !ok {
+			return "", 0, nil, nil, nil, ErrInvalidHash
+		}
+	}
+	if salt, digest, ok = parsePHCSaltAndHash(phc); !ok {
+		return "", 0, nil, nil, nil, ErrInvalidHash
+	}
+	return phc.id, version, params, salt, digest, nil
+}
+
+// phcString holds the raw segments of a PHC string, as split by
+// splitPHCString. version (including its "v=" prefix) and params are empty
+// when absent; segments counts the salt and hash segments present, either
+// of which may be empty.
+type phcString struct {
+	id, version, params string
+	salt, hash          string
+	segments            int
+}
+
+// splitPHCString splits s into the segments of the PHC string format
+// without validating them. A segment after the identifier is the version
+// if it starts with "v=" and the parameters if it contains '='; the rest
+// are the salt and hash. It reports false if s does not start with '$' or
+// has more than two segments after the parameters. It scans in place, so
+// decoding does not allocate.
+func splitPHCString(s string) (phc phcString, ok bool) {
+	rest, ok := strings.CutPrefix(s, "$")
+	if !ok {
+		return phc, false
+	}
+
+	var more bool
+	phc.id, rest, more = strings.Cut(rest, "$")
+	if more && strings.HasPrefix(rest, "v=") {
+		phc.version, rest, more = strings.Cut(rest, "$")
+	}
+	if more {
+		if segment, next, nextMore := strings.Cut(rest, "$"); strings.IndexByte(segment, '=') >= 0 {
+			phc.params, rest, more = segment, next, nextMore
+		}
+	}
+	if more {
+		phc.salt, rest, more = strings.Cut(rest, "$")
+		phc.segments++
+	}
+	if more {
+		phc.hash, rest, more = strings.Cut(rest, "$")
+		phc.segments++
+	}
+	return phc, !more
+}
+
+// parsePHCVersion parses a "v=<decimal>" segment.
+func parsePHCVersion(segment string) (int, bool) {
+	v, err := strconv.ParseUint(strings.TrimPrefix(segment, "v="), 10, 31)
+	if err != nil {
+		return 0, false
+	}
+	return int(v), true
+}
+
+// parsePHCParams parses a comma-separated "name=value" segment, rejecting
+// invalid names, parameters without '=', values containing '=' and
+// duplicates.
+func parsePHCParams(segment string) (map[string]string, bool) {
+	params := make(map[string]string)
+	for rest, more := segment, true; more; {
+		var param string
+		param, rest, more = strings.Cut(rest, ",")
+		name, value, found := strings.Cut(param, "=")
+		if !found || !isPHCIdentifier(name) || strings.IndexByte(value, '=') >= 0 {
+			return nil, false
+		}
+		if _, dup := params[name]; dup {
+			return nil, false
+		}
+		params[name] = value
+	}
+	return params, true
+}
+
+// parsePHCSaltAndHash decodes the optional salt and hash segments, which
+// must be non-empty when present.
+func parsePHCSaltAndHash(phc phcString) (salt, digest []byte, ok bool) {
+	var err error
+	if phc.segments > 0 {
+		if phc.salt == "" {
+			return nil, nil, false
+		}
+		if salt, err = decodeBase64Segment(base64.RawStdEncoding, phc.salt); err != nil {
+			return nil, nil, false
+		}
+	}
+	if phc.segments > 1 {
+		if phc.hash == "" {
+			return nil, nil, false
+		}
+		if digest, err = decodeBase64Segment(base64.RawStdEncoding, phc.hash); err != nil {
+			return nil, nil, false
+		}
+	}
+	return salt, digest, true
+}
@@ -0,0 +1,80 @@
+package argon2id
+
+import (
+	"bytes"
+	"maps"
+	"testing"
+)
+
+func TestParsePHC(t *testing.T) {
+	tests := []struct {
+		name    string
+		hash    string
+		scheme  string
+		version int
+		params  map[string]string
+		salt    string
+		digest  string
+	}{
+		{
+			"argon2id",
+			"$argon2id$v=19$m=65536,t=3,p=2$MDEyMzQ1Njc4OWFiY2RlZg$UqcjN8FEhR9C3aVPAePbwhjlawq6mqKGGOFCbIG7i8U",
+			"argon2id", 19, map[string]string{"m": "65536", "t": "3", "p": "2"},
+			"0123456789abcdef", "\x52\xa7\x23\x37\xc1\x44\x85\x1f\x42\xdd\xa5\x4f\x01\xe3\xdb\xc2\x18\xe5\x6b\x0a\xba\x9a\xa2\x86\x18\xe1\x42\x6c\x81\xbb\x8b\xc5",
+		},
+		{
+			"argon2i",
+			"$argon2i$v=19$m=8,t=1,p=1$c2FsdHNhbHQ$YC9+jJCrQhs5R6db7LlN8Q",
+			"argon2i", 19, map[string]string{"m": "8", "t": "1", "p": "1"},
+			"saltsalt", "\x60\x2f\x7e\x8c\x90\xab\x42\x1b\x39\x47\xa7\x5b\xec\xb9\x4d\xf1",
+		},
+		{
+			"synthetic scheme without version",
+			"$toy-kdf$rounds=7,mode=fast$c2FsdA$ZGlnZXN0",
+			"toy-kdf", 0, map[string]string{"rounds": "7", "mode": "fast"},
+			"salt", "digest",
+		},
+		{"scheme only", "$toy-kdf", "toy-kdf", 0, nil, "", ""},
+		{"salt without digest", "$toy-kdf$v=2$c2FsdA", "toy-kdf", 2, nil, "salt", ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			scheme, version, params, salt, digest, err := ParsePHC(tt.hash)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if scheme != tt.scheme || version != tt.version {
+				t.Errorf("expected %s v%d, got %s v%d", tt.scheme, tt.version, scheme, version)
+			}
+			if !maps.Equal(params, tt.params) {
+				t.Errorf("expected params %v, got %v", tt.params, params)
+			}
+			if !bytes.Equal(salt, []byte(tt.salt)) || !bytes.Equal(digest, []byte(tt.digest)) {
+				t.Errorf("expected salt %q and digest %x, got %q and %x", tt.salt, tt.digest, salt, digest)
+			}
+		})
+	}
+}
+
+func TestParsePHCInvalid(t *testing.T) {
+	for _, hash := range []string{
+		"",
+		"argon2id$v=19$m=1,t=1,p=1$c2FsdA$ZGlnZXN0",
+		"$",
+		"$Argon2id$v=19",
+		"$argon2id$v=x$m=1",
+		"$argon2id$v=19$m=1,m=2$c2FsdA$ZGlnZXN0",
+		"$argon2id$v=19$M=1$c2FsdA$ZGlnZXN0",
+		"$argon2id$v=19$m=1=2$c2FsdA$ZGlnZXN0",
+		"$toy$a=1,b$c2FsdA$ZGlnZXN0",
+		"$argon2id$v=19$m=1$$ZGlnZXN0",
+		"$argon2id$v=19$m=1$c2FsdA$",
+		"$argon2id$v=19$m=1$c2FsdA$ZGlnZXN0$extra",
+		"$argon2id$v=19$m=1$not*base64$ZGlnZXN0",
+	} {
+		if _, _, _, _, _, err := ParsePHC(hash); err != ErrInvalidHash {
+			t.Errorf("%q: expected ErrInvalidHash, got %v", hash, err)
+		}
+	}
+}
@@ -0,0 +1,64 @@
+package argon2id
+
+import "sync"
+
+// CompareResult is the outcome of comparing one HashPasswordPair in
+// ComparePipeline.
+type CompareResult struct {
+	Err   error // Result of CompareHashAndPassword; nil on a match
+	Index int   // Position of the pair in the input, starting at 0
+}
+
+// ComparePipeline verifies each pair received from in with
+// CompareHashAndPassword and sends one CompareResult per pair as soon as it
+// completes, e.g. for a credential-auditing pipeline whose input is too
+// large or open-ended for a slice. Results arrive in completion order; use
+// Index to match them to their pair.
+//
+// At most concurrency comparisons run at once (at least 1), and the
+// package-wide SetMaxConcurrency limit still applies on top, so a pipeline
+// cannot exceed the memory budget shared with other hashing. The returned
+// channel is closed after in is closed and every pair has been reported.
+// The caller must keep receiving from it until then, or the pipeline
+// blocks.
+func ComparePipeline(in <-chan HashPasswordPair, concurrency int) <-chan CompareResult {
+	return comparePipeline(in, concurrency, CompareHashAndPassword)
+}
+
+// comparePipeline is ComparePipeline with a pluggable comparison.
+func comparePipeline(in <-chan HashPasswordPair, concurrency int, compare func(hashedPassword, password []byte) error) <-chan CompareResult {
+	concurrency = max(concurrency, 1)
+
+	type job struct {
+		pair  HashPasswordPair
+		index int
+	}
+	jobs := make(chan job)
+	out := make(chan CompareResult)
+
+	go func() {
+		defer close(jobs)
+		index := 0
+		for pair := range in {
+			jobs <- job{pair: pair, index: index}
+			index++
+		}
+	}()
+
+	var wg sync.WaitGroup
+	for range concurrency {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := range jobs {
+				out <- CompareResult{Index: j.index, Err: compare(j.pair.Hash, j.pair.Password)}
+			}
+		}()
+	}
+	go func() {
+		wg.Wait()
+		close(out)
+	}()
+
+	return out
+}
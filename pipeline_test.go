@@ -0,0 +1,91 @@
+//go:build !fips && !goexperiment.boringcrypto
+
+package argon2id
+
+import (
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestComparePipeline(t *testing.T) {
+	params := &Params{Time: 1, Memory: 1024, Threads: 1, KeyLen: 32}
+	hash, err := GenerateFromPassword([]byte("password"), params)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	const n = 20
+	in := make(chan HashPasswordPair)
+	go func() {
+		defer close(in)
+		for i := range n {
+			password := "password"
+			if i%3 == 0 {
+				password = "wrong"
+			}
+			in <- HashPasswordPair{Hash: hash, Password: []byte(password)}
+		}
+	}()
+
+	seen := make(map[int]bool)
+	for result := range ComparePipeline(in, 4) {
+		if seen[result.Index] {
+			t.Errorf("index %d reported twice", result.Index)
+		}
+		seen[result.Index] = true
+
+		want := error(nil)
+		if result.Index%3 == 0 {
+			want = ErrMismatchedHashAndPassword
+		}
+		if result.Err != want {
+			t.Errorf("index %d: expected %v, got %v", result.Index, want, result.Err)
+		}
+	}
+	if len(seen) != n {
+		t.Errorf("expected %d results, got %d", n, len(seen))
+	}
+}
+
+func TestComparePipelineConcurrency(t *testing.T) {
+	for _, concurrency := range []int{0, 1, 3} {
+		var inFlight, peak atomic.Int32
+		compare := func(_, _ []byte) error {
+			now := inFlight.Add(1)
+			for {
+				old := peak.Load()
+				if now <= old || peak.CompareAndSwap(old, now) {
+					break
+				}
+			}
+			time.Sleep(time.Millisecond)
+			inFlight.Add(-1)
+			return nil
+		}
+
+		in := make(chan HashPasswordPair, 30)
+		for range cap(in) {
+			in <- HashPasswordPair{}
+		}
+		close(in)
+
+		count := 0
+		for range comparePipeline(in, concurrency, compare) {
+			count++
+		}
+		if count != cap(in) {
+			t.Errorf("concurrency %d: expected %d results, got %d", concurrency, cap(in), count)
+		}
+		if limit := int32(max(concurrency, 1)); peak.Load() > limit {
+			t.Errorf("concurrency %d: %d comparisons ran at once", concurrency, peak.Load())
+		}
+	}
+
+	// An empty input closes the output without results
+	in := make(chan HashPasswordPair)
+	close(in)
+	if _, ok := <-ComparePipeline(in, 2); ok {
+		t.Error("expected no results for an empty input")
+	}
+}
@@ -0,0 +1,86 @@
+package argon2id
+
+import (
+	"runtime"
+	"sync"
+)
+
+// hashPool holds the job queue of the hashing pool, nil when disabled.
+// Submitters hold poolMu for reading while they enqueue, so SetHashingPool
+// never closes a queue that is being sent to.
+var (
+	poolMu   sync.RWMutex
+	poolJobs chan func()
+)
+
+// SetHashingPool runs every Argon2 computation on one of size dedicated
+// worker goroutines, each locked to its own OS thread, instead of on the
+// calling goroutine. A size <= 0 removes the pool, which is the default.
+//
+// The pool isolates hashing from the rest of the program: at most size
+// computations run at once, on threads that do nothing else, and the
+// goroutines handling other requests are never the ones doing the
+// memory-hard work. Go still schedules the workers onto GOMAXPROCS
+// processors alongside other goroutines, so the pool bounds how much of
+// the machine hashing can take rather than reserving cores; combine it with
+// a size below GOMAXPROCS to leave headroom for everything else. Note that
+// Params.Threads > 1 makes each computation fan out to further goroutines
+// that are not locked.
+//
+// Calls queue until a worker is free. The concurrency limit of
+// SetMaxConcurrency still applies before a computation is queued. Resizing
+// lets computations already queued on the old pool finish there.
+func SetHashingPool(size int) {
+	poolMu.Lock()
+	defer poolMu.Unlock()
+
+	if poolJobs != nil {
+		close(poolJobs)
+		poolJobs = nil
+	}
+	if size <= 0 {
+		return
+	}
+
+	jobs := make(chan func())
+	for range size {
+		go hashWorker(jobs)
+	}
+	poolJobs = jobs
+}
+
+// hashWorker runs jobs on a locked OS thread until jobs is closed. The
+// thread exits with the goroutine, since it is never unlocked.
+func hashWorker(jobs <-chan func()) {
+	runtime.LockOSThread()
+	for job := range jobs {
+		job()
+	}
+}
+
+// runHashJob runs job on the hashing pool if there is one, and on the
+// calling goroutine otherwise, returning once it has finished. A panic in
+// job is re-raised on the calling goroutine, as it would be without a pool.
+func runHashJob(job func()) {
+	poolMu.RLock()
+	jobs := poolJobs
+	if jobs == nil {
+		poolMu.RUnlock()
+		job()
+		return
+	}
+
+	done := make(chan struct{})
+	var panicked any
+	jobs <- func() {
+		defer close(done)
+		defer func() { panicked = recover() }()
+		job()
+	}
+	poolMu.RUnlock()
+	<-done
+
+	if panicked != nil {
+		panic(panicked)
+	}
+}
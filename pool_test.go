@@ -0,0 +1,128 @@
+//go:build !fips && !goexperiment.boringcrypto
+
+package argon2id
+
+import (
+	"slices"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"golang.org/x/crypto/argon2"
+)
+
+func TestSetHashingPool(t *testing.T) {
+	SetHashingPool(2)
+	t.Cleanup(func() { SetHashingPool(0) })
+
+	params := &Params{Time: 1, Memory: 1024, Threads: 1, KeyLen: 32}
+	hash, err := GenerateFromPassword([]byte("password"), params)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := CompareHashAndPassword(hash, []byte("password")); err != nil {
+		t.Errorf("expected hash to verify on the pool, got %v", err)
+	}
+
+	// No more than size computations run at once
+	var inFlight, peak atomic.Int32
+	h := NewHasher(params)
+	h.KDF = func(password, salt []byte, time, memory uint32, threads uint8, keyLen uint32) []byte {
+		n := inFlight.Add(1)
+		defer inFlight.Add(-1)
+		for {
+			p := peak.Load()
+			if n <= p || peak.CompareAndSwap(p, n) {
+				break
+			}
+		}
+		return argon2.IDKey(password, salt, time, memory, threads, keyLen)
+	}
+	var wg sync.WaitGroup
+	for range 8 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if err := h.CompareHashAndPassword(hash, []byte("password")); err != nil {
+				t.Error(err)
+			}
+		}()
+	}
+	wg.Wait()
+	if peak.Load() > 2 {
+		t.Errorf("expected at most 2 concurrent computations, got %d", peak.Load())
+	}
+
+	// Panics reach the caller
+	h.KDF = func([]byte, []byte, uint32, uint32, uint8, uint32) []byte { panic("kdf failed") }
+	func() {
+		defer func() {
+			if recover() != "kdf failed" {
+				t.Error("expected the KDF panic to reach the caller")
+			}
+		}()
+		_ = h.CompareHashAndPassword(hash, []byte("password"))
+	}()
+
+	// Resizing and removing keep hashing working
+	SetHashingPool(1)
+	if err := CompareHashAndPassword(hash, []byte("password")); err != nil {
+		t.Errorf("expected hash to verify after resizing, got %v", err)
+	}
+	SetHashingPool(0)
+	if err := CompareHashAndPassword(hash, []byte("password")); err != nil {
+		t.Errorf("expected hash to verify without a pool, got %v", err)
+	}
+}
+
+// BenchmarkHashingPoolTailLatency measures how promptly a latency-sensitive
+// goroutine (a 1ms ticker standing in for request handling) is scheduled
+// while 8 goroutines hash continuously, with and without a hashing pool.
+// The p99-lag metric is how late the ticker fired at the 99th percentile.
+// The difference depends on GOMAXPROCS: the pool helps most when its size
+// leaves processors free for other goroutines.
+func BenchmarkHashingPoolTailLatency(b *testing.B) {
+	params := &Params{Time: 1, Memory: 8 * 1024, Threads: 1, KeyLen: 32}
+	for _, pool := range []int{0, 1} {
+		name := "no-pool"
+		if pool > 0 {
+			name = "pool=1"
+		}
+		b.Run(name, func(b *testing.B) {
+			SetHashingPool(pool)
+			defer SetHashingPool(0)
+
+			stop := make(chan struct{})
+			var wg sync.WaitGroup
+			for range 8 {
+				wg.Add(1)
+				go func() {
+					defer wg.Done()
+					for {
+						select {
+						case <-stop:
+							return
+						default:
+							_, _ = GenerateFromPassword([]byte("password"), params)
+						}
+					}
+				}()
+			}
+
+			lags := make([]time.Duration, 0, b.N)
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				start := time.Now()
+				time.Sleep(time.Millisecond)
+				lags = append(lags, time.Since(start)-time.Millisecond)
+			}
+			b.StopTimer()
+			close(stop)
+			wg.Wait()
+
+			slices.Sort(lags)
+			b.ReportMetric(float64(lags[len(lags)*99/100]), "p99-lag-ns")
+		})
+	}
+}
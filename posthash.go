@@ -0,0 +1,76 @@
+package argon2id
+
+import (
+	"errors"
+	"strings"
+)
+
+// DigestTransform wraps the Argon2 digest before it is stored, e.g. by
+// encrypting it with a key held in an HSM or KMS, so a leaked hash is
+// useless without access to that key. Unwrap must invert Wrap.
+//
+// Set it as Params.PostHash. Wrapped hashes carry a "data=" marker in the
+// PHC parameters and can only be verified by a Hasher whose Params.PostHash
+// unwraps them; CompareHashAndPassword returns ErrPostHashRequired.
+//
+// The stored value depends on the wrapping key, which has consequences for
+// key rotation: hashes wrapped under an old key stay verifiable only while
+// Unwrap can still use that key. Because Unwrap recovers the plain digest,
+// hashes can be rewrapped under a new key offline, without the users'
+// passwords: Unwrap the stored digest with the old key and Wrap it with the
+// new one. A transform that encodes a key identifier in its output makes
+// rotating gradually easier. Losing the key makes every wrapped hash
+// unverifiable.
+//
+// Implementations must be safe for concurrent use. The wrapped digest must
+// be between MinKeyLen and MaxKeyLen bytes. Use a comparable type, such as a
+// pointer to a struct, so Params holding it can still be compared with ==
+// (see Params).
+type DigestTransform interface {
+	Wrap(digest []byte) ([]byte, error)
+	Unwrap(wrapped []byte) ([]byte, error)
+}
+
+// ErrPostHashRequired is returned when verifying a hash whose digest was
+// wrapped by a DigestTransform without one configured.
+var ErrPostHashRequired = errors.New("argon2id: hash digest is wrapped; verify with a Hasher configured with its PostHash transform")
+
+// postHashMarker is the PHC parameter recording that the digest is wrapped
+// ("data=" with base64 of "posthash").
+const postHashMarker = "data=cG9zdGhhc2g"
+
+// hasPostHashMarker reports whether decoded Extra parameters mark the
+// digest as wrapped.
+func hasPostHashMarker(extra string) bool {
+	for _, param := range strings.Split(extra, ",") {
+		if param == postHashMarker {
+			return true
+		}
+	}
+	return false
+}
+
+// unwrapDigest returns the plain digest of a decoded hash, unwrapping it
+// with fallback's PostHash if the hash is marked as wrapped, and updates
+// params.KeyLen to match.
+func unwrapDigest(params *Params, digest []byte, fallback *Params) ([]byte, error) {
+	if !hasPostHashMarker(params.Extra) {
+		return digest, nil
+	}
+	if fallback == nil || fallback.PostHash == nil {
+		return nil, ErrPostHashRequired
+	}
+
+	digest, err := fallback.PostHash.Unwrap(digest)
+	if err != nil {
+		return nil, err
+	}
+	if len(digest) < MinKeyLen || len(digest) > MaxKeyLen {
+		return nil, ErrInvalidHash
+	}
+	if err := checkKeyLenParam(params.Extra, len(digest)); err != nil {
+		return nil, err
+	}
+	params.KeyLen = uint32(len(digest)) // #nosec G115 - bounded by MaxKeyLen
+	return digest, nil
+}
@@ -0,0 +1,124 @@
+//go:build !fips && !goexperiment.boringcrypto
+
+package argon2id
+
+import (
+	"bytes"
+	"errors"
+	"strings"
+	"testing"
+)
+
+// xorTransform is a toy DigestTransform standing in for an HSM.
+type xorTransform struct {
+	key byte
+}
+
+func (x xorTransform) Wrap(digest []byte) ([]byte, error) {
+	wrapped := make([]byte, len(digest))
+	for i, b := range digest {
+		wrapped[i] = b ^ x.key
+	}
+	return wrapped, nil
+}
+
+func (x xorTransform) Unwrap(wrapped []byte) ([]byte, error) {
+	return x.Wrap(wrapped)
+}
+
+type failingTransform struct{}
+
+var errHSMUnavailable = errors.New("hsm unavailable")
+
+func (failingTransform) Wrap([]byte) ([]byte, error)   { return nil, errHSMUnavailable }
+func (failingTransform) Unwrap([]byte) ([]byte, error) { return nil, errHSMUnavailable }
+
+func TestPostHash(t *testing.T) {
+	params := &Params{Time: 1, Memory: 1024, Threads: 1, KeyLen: 32, PostHash: xorTransform{key: 0x5c}}
+	h := NewHasher(params)
+
+	hash, err := h.GenerateFromPassword([]byte("password"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(hash), ","+postHashMarker+"$") {
+		t.Errorf("expected %s to carry the PostHash marker", hash)
+	}
+
+	if err := h.CompareHashAndPassword(hash, []byte("password")); err != nil {
+		t.Errorf("expected wrapped hash to verify with the transform, got %v", err)
+	}
+	if err := h.CompareHashAndPassword(hash, []byte("wrong")); err != ErrMismatchedHashAndPassword {
+		t.Errorf("expected %v, got %v", ErrMismatchedHashAndPassword, err)
+	}
+
+	// Without the transform verification fails clearly
+	if err := CompareHashAndPassword(hash, []byte("password")); err != ErrPostHashRequired {
+		t.Errorf("expected %v, got %v", ErrPostHashRequired, err)
+	}
+
+	// With a different key it does not match
+	other := NewHasher(&Params{Time: 1, Memory: 1024, Threads: 1, KeyLen: 32, PostHash: xorTransform{key: 0x36}})
+	if err := other.CompareHashAndPassword(hash, []byte("password")); err != ErrMismatchedHashAndPassword {
+		t.Errorf("expected %v with the wrong key, got %v", ErrMismatchedHashAndPassword, err)
+	}
+
+	// Unwrapped hashes created before the transform was enabled still verify
+	plain, err := GenerateFromPassword([]byte("password"), &Params{Time: 1, Memory: 1024, Threads: 1, KeyLen: 32})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := h.CompareHashAndPassword(plain, []byte("password")); err != nil {
+		t.Errorf("expected unmarked hash to verify, got %v", err)
+	}
+}
+
+func TestPostHashRewrap(t *testing.T) {
+	oldKey, newKey := xorTransform{key: 0x5c}, xorTransform{key: 0x36}
+	hash, err := GenerateFromPassword([]byte("password"), &Params{Time: 1, Memory: 1024, Threads: 1, KeyLen: 32, PostHash: oldKey})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Rotate offline: unwrap with the old key, wrap with the new one
+	params, salt, wrapped, err := decodeHash(string(hash), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	digest, _ := oldKey.Unwrap(wrapped)
+	rewrapped, _ := newKey.Wrap(digest)
+	params.PostHash = newKey
+	rotated := encodeHash(params, salt, rewrapped)
+
+	if bytes.Equal(rotated, hash) {
+		t.Fatal("expected the stored value to change")
+	}
+	h := NewHasher(&Params{Time: 1, Memory: 1024, Threads: 1, KeyLen: 32, PostHash: newKey})
+	if err := h.CompareHashAndPassword(rotated, []byte("password")); err != nil {
+		t.Errorf("expected rewrapped hash to verify with the new key, got %v", err)
+	}
+	if strings.Count(string(rotated), postHashMarker) != 1 {
+		t.Errorf("expected a single marker in %s", rotated)
+	}
+}
+
+func TestPostHashErrors(t *testing.T) {
+	_, err := GenerateFromPassword([]byte("password"), &Params{Time: 1, Memory: 1024, Threads: 1, KeyLen: 32, PostHash: failingTransform{}})
+	if err != errHSMUnavailable {
+		t.Errorf("expected %v from Wrap, got %v", errHSMUnavailable, err)
+	}
+
+	_, err = GenerateFromPassword([]byte("password"), &Params{Time: 1, Memory: 1024, Threads: 1, KeyLen: 32, PostHash: xorTransform{}, Encoding: EncodingRaw})
+	if err == nil {
+		t.Error("expected error for PostHash with a compact encoding")
+	}
+
+	hash, err := GenerateFromPassword([]byte("password"), &Params{Time: 1, Memory: 1024, Threads: 1, KeyLen: 32, PostHash: xorTransform{}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	h := NewHasher(&Params{Time: 1, Memory: 1024, Threads: 1, KeyLen: 32, PostHash: failingTransform{}})
+	if err := h.CompareHashAndPassword(hash, []byte("password")); err != errHSMUnavailable {
+		t.Errorf("expected %v from Unwrap, got %v", errHSMUnavailable, err)
+	}
+}
@@ -0,0 +1,101 @@
+package argon2id
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// ErrKeyIDMismatch is returned by CompareHashAndPasswordHMAC when a hash was
+// not generated with the given key, judging by its "keyid=" parameter. It
+// wraps ErrMismatchedHashAndPassword, so errors.Is(err,
+// ErrMismatchedHashAndPassword) still holds.
+var ErrKeyIDMismatch = fmt.Errorf("%w: hash was generated with a different key", ErrMismatchedHashAndPassword)
+
+// GenerateFromPasswordHMAC is like GenerateFromPassword, but hashes
+// HMAC-SHA256(key, password) instead of the password, so the stored hash
+// cannot be cracked without the key (a "pepper"). Keep the key outside the
+// database, e.g. in a secrets manager.
+//
+// The hash carries a "keyid=" PHC parameter identifying the key (see KeyID),
+// never the key itself, so keys can be rotated: keep old keys by id, verify
+// each hash with the key named by ExtractParams(hash).KeyID(), and rehash
+// with the new key on the next successful login. The key must not be empty
+// and params must use EncodingPHC, the only encoding that can carry the id.
+func GenerateFromPasswordHMAC(password, key []byte, params *Params) ([]byte, error) {
+	if params == nil {
+		params = DefaultParams()
+	}
+	if len(key) == 0 {
+		return nil, errors.New("argon2id: HMAC key must not be empty")
+	}
+	if params.Encoding != EncodingPHC {
+		return nil, fmt.Errorf("argon2id: HMAC pre-hashing requires EncodingPHC, not %s", params.Encoding)
+	}
+	if params.RejectEmptyPassword && len(password) == 0 {
+		return nil, ErrEmptyPassword
+	}
+
+	p := params.Clone()
+	p.Extra = withKeyID(p.Extra, KeyID(key))
+	return GenerateFromPassword(prehash(key, password), p)
+}
+
+// CompareHashAndPasswordHMAC verifies a hash generated by
+// GenerateFromPasswordHMAC. It returns ErrKeyIDMismatch without hashing if
+// the hash's "keyid=" parameter does not name key, including for hashes
+// generated without a key.
+func CompareHashAndPasswordHMAC(hashedPassword, password, key []byte) error {
+	params, err := ExtractParams(hashedPassword)
+	if err != nil {
+		return err
+	}
+	if len(key) == 0 || params.KeyID() != KeyID(key) {
+		return ErrKeyIDMismatch
+	}
+	return CompareHashAndPassword(hashedPassword, prehash(key, password))
+}
+
+// KeyID returns the non-secret identifier of an HMAC key that
+// GenerateFromPasswordHMAC records in the "keyid=" parameter: 8 bytes of
+// HMAC-SHA256(key, "argon2id keyid"), base64-encoded. It is safe to store
+// and log, and lets the right key be looked up during rotation.
+func KeyID(key []byte) string {
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte("argon2id keyid"))
+	return base64.RawStdEncoding.EncodeToString(mac.Sum(nil)[:8])
+}
+
+// KeyID returns the value of the PHC "keyid=" parameter in p.Extra, or ""
+// if there is none.
+func (p *Params) KeyID() string {
+	for _, param := range strings.Split(p.Extra, ",") {
+		if id, ok := strings.CutPrefix(param, "keyid="); ok {
+			return id
+		}
+	}
+	return ""
+}
+
+// withKeyID returns extra with any "keyid=" parameter replaced by id.
+func withKeyID(extra, id string) string {
+	var kept []string
+	if extra != "" {
+		for _, param := range strings.Split(extra, ",") {
+			if !strings.HasPrefix(param, "keyid=") {
+				kept = append(kept, param)
+			}
+		}
+	}
+	return strings.Join(append(kept, "keyid="+id), ",")
+}
+
+// prehash returns HMAC-SHA256(key, password).
+func prehash(key, password []byte) []byte {
+	mac := hmac.New(sha256.New, key)
+	mac.Write(password)
+	return mac.Sum(nil)
+}
@@ -0,0 +1,114 @@
+//go:build !fips && !goexperiment.boringcrypto
+
+package argon2id
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestGenerateFromPasswordHMAC(t *testing.T) {
+	params := &Params{Time: 1, Memory: 1024, Threads: 1, KeyLen: 32}
+	key := []byte("0123456789abcdef0123456789abcdef")
+
+	hash, err := GenerateFromPasswordHMAC([]byte("password"), key, params)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := CompareHashAndPasswordHMAC(hash, []byte("password"), key); err != nil {
+		t.Errorf("expected match, got %v", err)
+	}
+	if err := CompareHashAndPasswordHMAC(hash, []byte("wrong"), key); err != ErrMismatchedHashAndPassword {
+		t.Errorf("expected ErrMismatchedHashAndPassword, got %v", err)
+	}
+
+	// The hash records the key id, not the key, and needs the key to verify
+	if !strings.Contains(string(hash), ",keyid="+KeyID(key)+"$") {
+		t.Errorf("expected keyid parameter in %s", hash)
+	}
+	if strings.Contains(string(hash), string(key)) {
+		t.Error("hash must not contain the key")
+	}
+	if err := CompareHashAndPassword(hash, []byte("password")); err != ErrMismatchedHashAndPassword {
+		t.Errorf("expected plain verification to fail, got %v", err)
+	}
+
+	decoded, err := ExtractParams(hash)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if decoded.KeyID() != KeyID(key) {
+		t.Errorf("expected KeyID %q, got %q", KeyID(key), decoded.KeyID())
+	}
+	if params.Extra != "" {
+		t.Errorf("params were modified: Extra = %q", params.Extra)
+	}
+
+	// Regenerating from decoded params replaces the key id
+	rotated := []byte("fedcba9876543210fedcba9876543210")
+	hash2, err := GenerateFromPasswordHMAC([]byte("password"), rotated, decoded)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Count(string(hash2), "keyid=") != 1 {
+		t.Errorf("expected a single keyid parameter in %s", hash2)
+	}
+	if err := CompareHashAndPasswordHMAC(hash2, []byte("password"), rotated); err != nil {
+		t.Errorf("expected match with rotated key, got %v", err)
+	}
+}
+
+func TestCompareHashAndPasswordHMACWrongKey(t *testing.T) {
+	params := &Params{Time: 1, Memory: 1024, Threads: 1, KeyLen: 32}
+	key := []byte("0123456789abcdef0123456789abcdef")
+
+	hash, err := GenerateFromPasswordHMAC([]byte("password"), key, params)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = CompareHashAndPasswordHMAC(hash, []byte("password"), []byte("another key"))
+	if err != ErrKeyIDMismatch {
+		t.Errorf("expected ErrKeyIDMismatch, got %v", err)
+	}
+	if !errors.Is(err, ErrMismatchedHashAndPassword) {
+		t.Error("expected ErrKeyIDMismatch to wrap ErrMismatchedHashAndPassword")
+	}
+	if err := CompareHashAndPasswordHMAC(hash, []byte("password"), nil); err != ErrKeyIDMismatch {
+		t.Errorf("expected ErrKeyIDMismatch for an empty key, got %v", err)
+	}
+
+	// A key id forged to name the key does not help without it
+	forged := strings.Replace(string(hash), KeyID(key), KeyID([]byte("another key")), 1)
+	if err := CompareHashAndPasswordHMAC([]byte(forged), []byte("password"), []byte("another key")); err != ErrMismatchedHashAndPassword {
+		t.Errorf("expected ErrMismatchedHashAndPassword, got %v", err)
+	}
+
+	plain, err := GenerateFromPassword([]byte("password"), params)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := CompareHashAndPasswordHMAC(plain, []byte("password"), key); err != ErrKeyIDMismatch {
+		t.Errorf("expected ErrKeyIDMismatch for a hash without keyid, got %v", err)
+	}
+}
+
+func TestGenerateFromPasswordHMACValidation(t *testing.T) {
+	params := &Params{Time: 1, Memory: 1024, Threads: 1, KeyLen: 32}
+	key := []byte("key")
+
+	if _, err := GenerateFromPasswordHMAC([]byte("password"), nil, params); err == nil {
+		t.Error("expected error for an empty key")
+	}
+	raw := params.Clone()
+	raw.Encoding = EncodingRaw
+	if _, err := GenerateFromPasswordHMAC([]byte("password"), key, raw); err == nil {
+		t.Error("expected error for EncodingRaw")
+	}
+	strict := params.Clone()
+	strict.RejectEmptyPassword = true
+	if _, err := GenerateFromPasswordHMAC(nil, key, strict); err != ErrEmptyPassword {
+		t.Errorf("expected ErrEmptyPassword, got %v", err)
+	}
+}
@@ -0,0 +1,70 @@
+package argon2id
+
+import (
+	"errors"
+	"fmt"
+	"math/bits"
+	"strings"
+)
+
+// ErrSaltNotRepresentable is returned by ReferenceCommand when a salt
+// contains a zero byte, which cannot be passed as a command-line argument.
+var ErrSaltNotRepresentable = errors.New("argon2id: salt contains a zero byte and cannot be passed to the argon2 CLI")
+
+// ReferenceCommand returns a shell command that recomputes hashedPassword
+// with the reference argon2 command-line tool, for cross-checking this
+// package against it, e.g.:
+//
+//	echo -n 'PASSWORD' | argon2 $'\x91\xf3...' -id -v 13 -t 1 -m 10 -p 1 -l 32
+//
+// Replace PASSWORD with the password; the tool's "Encoded:" output should
+// then equal hashedPassword (for PHC hashes in the standard alphabet).
+// Memory is given with -m (log2 of KiB) when it is a power of two and with
+// -k (KiB, supported by newer releases of the tool) otherwise. The salt is
+// quoted for bash, using $'...' escapes for non-printable bytes; a salt
+// containing a zero byte returns ErrSaltNotRepresentable. Compact hashes
+// report DefaultParams() costs, and hashes wrapped by a DigestTransform
+// return ErrPostHashRequired, as the tool cannot reproduce them.
+func ReferenceCommand(hashedPassword []byte) (string, error) {
+	params, salt, _, err := decodeHash(string(hashedPassword), nil)
+	if err != nil {
+		return "", err
+	}
+	if hasPostHashMarker(params.Extra) {
+		return "", ErrPostHashRequired
+	}
+	if strings.IndexByte(string(salt), 0) >= 0 {
+		return "", ErrSaltNotRepresentable
+	}
+
+	memory := fmt.Sprintf("-k %d", params.Memory)
+	if bits.OnesCount32(params.Memory) == 1 {
+		memory = fmt.Sprintf("-m %d", bits.TrailingZeros32(params.Memory))
+	}
+
+	return fmt.Sprintf("echo -n 'PASSWORD' | argon2 %s -id -v 13 -t %d %s -p %d -l %d",
+		shellQuote(salt), params.Time, memory, params.Threads, params.KeyLen), nil
+}
+
+// shellQuote quotes s as a single bash word: in single quotes if it is
+// printable ASCII without a quote, and as $'\xNN...' otherwise.
+func shellQuote(s []byte) string {
+	printable := true
+	for _, c := range s {
+		if c < 0x20 || c > 0x7e || c == '\'' {
+			printable = false
+			break
+		}
+	}
+	if printable {
+		return "'" + string(s) + "'"
+	}
+
+	var b strings.Builder
+	b.WriteString("$'")
+	for _, c := range s {
+		fmt.Fprintf(&b, `\x%02x`, c)
+	}
+	b.WriteString("'")
+	return b.String()
+}
@@ -0,0 +1,59 @@
+//go:build !fips && !goexperiment.boringcrypto
+
+package argon2id
+
+import "testing"
+
+func TestReferenceCommand(t *testing.T) {
+	tests := []struct {
+		params *Params
+		salt   string
+		want   string
+	}{
+		{
+			&Params{Time: 2, Memory: 65536, Threads: 4, KeyLen: 32},
+			"somesaltsomesalt",
+			"echo -n 'PASSWORD' | argon2 'somesaltsomesalt' -id -v 13 -t 2 -m 16 -p 4 -l 32",
+		},
+		{
+			&Params{Time: 1, Memory: 1000, Threads: 1, KeyLen: 16},
+			"salt'with\\xquote",
+			`echo -n 'PASSWORD' | argon2 $'\x73\x61\x6c\x74\x27\x77\x69\x74\x68\x5c\x78\x71\x75\x6f\x74\x65' -id -v 13 -t 1 -k 1000 -p 1 -l 16`,
+		},
+	}
+
+	for _, tt := range tests {
+		hash, err := GenerateFromPasswordWithSalt([]byte("password"), []byte(tt.salt), tt.params)
+		if err != nil {
+			t.Fatal(err)
+		}
+		got, err := ReferenceCommand(hash)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got != tt.want {
+			t.Errorf("expected\n%s\ngot\n%s", tt.want, got)
+		}
+	}
+
+	zeroSalt := make([]byte, SaltLen)
+	hash, err := GenerateFromPasswordWithSalt([]byte("password"), zeroSalt, &Params{Time: 1, Memory: 1024, Threads: 1, KeyLen: 32})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := ReferenceCommand(hash); err != ErrSaltNotRepresentable {
+		t.Errorf("expected %v, got %v", ErrSaltNotRepresentable, err)
+	}
+
+	wrapped, err := GenerateFromPassword([]byte("password"), &Params{Time: 1, Memory: 1024, Threads: 1, KeyLen: 32, PostHash: xorTransform{key: 1}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := ReferenceCommand(wrapped); err != ErrPostHashRequired {
+		t.Errorf("expected %v, got %v", ErrPostHashRequired, err)
+	}
+
+	if _, err := ReferenceCommand([]byte("not a hash")); err == nil {
+		t.Error("expected a decode error")
+	}
+}
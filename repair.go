@@ -0,0 +1,96 @@
+package argon2id
+
+import (
+	"errors"
+	"strconv"
+	"strings"
+
+	"golang.org/x/crypto/argon2"
+)
+
+// TryRepair rescues hashes damaged by known encoder bugs during a migration
+// and returns them in canonical form, as GenerateFromPassword would encode
+// them.
+//
+// It recognizes hashes with padded base64 segments, version 19 hashes whose
+// version segment an encoder dropped and hashes whose salt and digest
+// segments are swapped.
+// The last is detected by the salt not decoding to SaltLen bytes, so a
+// swap cannot be told apart when the digest is itself SaltLen bytes long.
+// A repaired hash only has a valid structure: verify it against a known
+// password before storing it. If no interpretation decodes, the error from
+// decoding the hash as given is returned.
+//
+// Nothing in the package repairs hashes implicitly; CompareHashAndPassword
+// rejects the swapped form. It already reads a hash without a version
+// segment as version 19, which TryRepair only makes explicit.
+func TryRepair(hashedPassword []byte) ([]byte, error) {
+	hash := string(hashedPassword)
+	params, salt, digest, err := decodeHash(hash, nil)
+	if err != nil {
+		var ok bool
+		if params, salt, digest, ok = decodeRepaired(hash); !ok {
+			return nil, err
+		}
+	}
+
+	if hasPostHashMarker(params.Extra) {
+		// Keep the marker; the digest is re-encoded as stored
+		params.PostHash = wrappedDigest{}
+	}
+	return encodeHash(params, salt, digest), nil
+}
+
+// decodeRepaired decodes the first reinterpretation of a damaged hash that
+// is valid: with the version 19 segment restored, with salt and digest
+// swapped, or both.
+func decodeRepaired(hash string) (params *Params, salt, digest []byte, ok bool) {
+	var candidates []string
+	if versioned, ok := insertMissingVersion(hash); ok {
+		candidates = append(candidates, versioned)
+		hash = versioned
+	}
+	if swapped, ok := swapSaltAndDigest(hash); ok {
+		candidates = append(candidates, swapped)
+	}
+	for _, candidate := range candidates {
+		var err error
+		if params, salt, digest, err = decodeHash(candidate, nil); err == nil {
+			return params, salt, digest, true
+		}
+	}
+	return nil, nil, nil, false
+}
+
+// insertMissingVersion adds "v=19" to a PHC hash without a version segment.
+func insertMissingVersion(hash string) (string, bool) {
+	hash = stripSchemeLabel(trimLineEnding(hash))
+	phc, ok := splitPHCString(hash)
+	if !ok || phc.version != "" || phc.params == "" {
+		return "", false
+	}
+	return "$" + phc.id + "$v=" + strconv.Itoa(argon2.Version) + hash[len(phc.id)+1:], true
+}
+
+// swapSaltAndDigest exchanges the last two '$'-separated segments of hash.
+func swapSaltAndDigest(hash string) (string, bool) {
+	i := strings.LastIndexByte(hash, '$')
+	if i < 0 {
+		return "", false
+	}
+	j := strings.LastIndexByte(hash[:i], '$')
+	if j < 0 {
+		return "", false
+	}
+	return hash[:j+1] + hash[i+1:] + "$" + hash[j+1:i], true
+}
+
+// errWrappedDigest is returned by wrappedDigest, which is never applied.
+var errWrappedDigest = errors.New("argon2id: digest is already wrapped")
+
+// wrappedDigest marks a repaired hash's digest as wrapped so encodeHash
+// keeps its marker.
+type wrappedDigest struct{}
+
+func (wrappedDigest) Wrap([]byte) ([]byte, error)   { return nil, errWrappedDigest }
+func (wrappedDigest) Unwrap([]byte) ([]byte, error) { return nil, errWrappedDigest }
@@ -0,0 +1,81 @@
+//go:build !fips && !goexperiment.boringcrypto
+
+package argon2id
+
+import (
+	"encoding/base64"
+	"fmt"
+	"testing"
+
+	"golang.org/x/crypto/argon2"
+)
+
+func TestTryRepair(t *testing.T) {
+	salt := []byte("0123456789abcdef")
+	digest := argon2.IDKey([]byte("password"), salt, 2, 1024, 1, 32)
+	raw := base64.RawStdEncoding.EncodeToString
+	padded := base64.StdEncoding.EncodeToString
+	canonical := fmt.Sprintf("$argon2id$v=19$m=1024,t=2,p=1$%s$%s", raw(salt), raw(digest))
+
+	tests := []struct {
+		name string
+		hash string
+	}{
+		{"canonical", canonical},
+		{"padded base64", fmt.Sprintf("$argon2id$v=19$m=1024,t=2,p=1$%s$%s", padded(salt), padded(digest))},
+		{"missing version", fmt.Sprintf("$argon2id$m=1024,t=2,p=1$%s$%s", raw(salt), raw(digest))},
+		{"swapped salt and digest", fmt.Sprintf("$argon2id$v=19$m=1024,t=2,p=1$%s$%s", raw(digest), raw(salt))},
+		{"swapped and padded", fmt.Sprintf("$argon2id$v=19$m=1024,t=2,p=1$%s$%s", padded(digest), padded(salt))},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			repaired, err := TryRepair([]byte(tt.hash))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if string(repaired) != canonical {
+				t.Errorf("expected %s, got %s", canonical, repaired)
+			}
+			if err := CompareHashAndPassword(repaired, []byte("password")); err != nil {
+				t.Errorf("expected repaired hash to verify, got %v", err)
+			}
+		})
+	}
+
+	// Repair is never implicit
+	swapped := tests[3].hash
+	if err := CompareHashAndPassword([]byte(swapped), []byte("password")); err != ErrInvalidHash {
+		t.Errorf("expected %v for a swapped hash, got %v", ErrInvalidHash, err)
+	}
+	unversioned := tests[2].hash
+	if err := CompareHashAndPassword([]byte(unversioned), []byte("password")); err != nil {
+		t.Errorf("expected an unversioned hash to verify as version 19, got %v", err)
+	}
+
+	for _, hash := range []string{"not a hash at all, not even close", "$argon2id$v=19$m=1024,t=2,p=1$!!!!$!!!!"} {
+		if _, err := TryRepair([]byte(hash)); err == nil {
+			t.Errorf("expected %q to be unrepairable", hash)
+		}
+	}
+}
+
+func TestTryRepairKeepsPostHashMarker(t *testing.T) {
+	transform := xorTransform{key: 0x5c}
+	h := NewHasher(&Params{Time: 1, Memory: 1024, Threads: 1, KeyLen: 32, PostHash: transform})
+	hash, err := h.GenerateFromPassword([]byte("password"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	swapped, _ := swapSaltAndDigest(string(hash))
+	repaired, err := TryRepair([]byte(swapped))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(repaired) != string(hash) {
+		t.Errorf("expected %s, got %s", hash, repaired)
+	}
+	if err := h.CompareHashAndPassword(repaired, []byte("password")); err != nil {
+		t.Errorf("expected repaired wrapped hash to verify, got %v", err)
+	}
+}
@@ -0,0 +1,55 @@
+package argon2id
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// MaxStreamLineLen is the longest line, in bytes, HashStream accepts.
+const MaxStreamLineLen = 4096
+
+// HashStream reads newline-delimited passwords from r, hashes each with
+// params and writes a "password<TAB>hash" line per password to w, in input
+// order. It is meant for seeding accounts from a file.
+//
+// A trailing "\r" is stripped from each line and blank lines are skipped.
+// Every hash goes through the package's concurrency limit (see
+// SetMaxConcurrency), so a large batch shares capacity with other hashing
+// rather than starving it. Errors stop the stream and name the failing
+// line; a line longer than MaxStreamLineLen returns ErrPasswordTooLong.
+// Lines already written before the error are left in w. If params is nil,
+// DefaultParams() will be used.
+func HashStream(r io.Reader, params *Params, w io.Writer) error {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 0, 64), MaxStreamLineLen+len("\r\n"))
+	n := 0
+	for scanner.Scan() {
+		n++
+		password := strings.TrimSuffix(scanner.Text(), "\r")
+		if password == "" {
+			continue
+		}
+		if len(password) > MaxStreamLineLen {
+			return fmt.Errorf("argon2id: line %d: %w", n, ErrPasswordTooLong)
+		}
+
+		hash, err := GenerateFromPassword([]byte(password), params)
+		if err != nil {
+			return fmt.Errorf("argon2id: line %d: %w", n, err)
+		}
+		if _, err := fmt.Fprintf(w, "%s\t%s\n", password, hash); err != nil {
+			return err
+		}
+	}
+
+	if err := scanner.Err(); err != nil {
+		if errors.Is(err, bufio.ErrTooLong) {
+			return fmt.Errorf("argon2id: line %d: %w", n+1, ErrPasswordTooLong)
+		}
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,60 @@
+//go:build !fips && !goexperiment.boringcrypto
+
+package argon2id
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestHashStream(t *testing.T) {
+	params := &Params{Time: 1, Memory: 1024, Threads: 1, KeyLen: 32}
+	input := "alice-secret\r\n\nbob secret\twith tab\ncarol"
+
+	var out strings.Builder
+	if err := HashStream(strings.NewReader(input), params, &out); err != nil {
+		t.Fatal(err)
+	}
+
+	lines := strings.Split(strings.TrimSuffix(out.String(), "\n"), "\n")
+	want := []string{"alice-secret", "bob secret\twith tab", "carol"}
+	if len(lines) != len(want) {
+		t.Fatalf("expected %d lines, got %q", len(want), lines)
+	}
+	for i, line := range lines {
+		// The hash contains no tab, so it follows the last one
+		sep := strings.LastIndexByte(line, '\t')
+		password, hash := line[:sep], line[sep+1:]
+		if password != want[i] {
+			t.Errorf("expected password %q, got %q", want[i], password)
+		}
+		if err := CompareHashAndPassword([]byte(hash), []byte(password)); err != nil {
+			t.Errorf("expected hash for %q to verify, got %v", password, err)
+		}
+	}
+}
+
+func TestHashStreamErrors(t *testing.T) {
+	params := &Params{Time: 1, Memory: 1024, Threads: 1, KeyLen: 32}
+
+	long := "ok\n" + strings.Repeat("x", MaxStreamLineLen+1) + "\nnever"
+	var out strings.Builder
+	err := HashStream(strings.NewReader(long), params, &out)
+	if !errors.Is(err, ErrPasswordTooLong) || !strings.Contains(err.Error(), "line 2") {
+		t.Errorf("expected %v on line 2, got %v", ErrPasswordTooLong, err)
+	}
+	if strings.Count(out.String(), "\n") != 1 {
+		t.Errorf("expected only the first line to be written, got %q", out.String())
+	}
+
+	exact := strings.Repeat("x", MaxStreamLineLen) + "\r\n"
+	if err := HashStream(strings.NewReader(exact), params, &strings.Builder{}); err != nil {
+		t.Errorf("expected a %d-byte line to be accepted, got %v", MaxStreamLineLen, err)
+	}
+
+	err = HashStream(strings.NewReader("a\nb\n"), &Params{Time: 0, Memory: 1024, Threads: 1, KeyLen: 32}, &strings.Builder{})
+	if err == nil || !strings.Contains(err.Error(), "line 1") {
+		t.Errorf("expected a params error on line 1, got %v", err)
+	}
+}
@@ -0,0 +1,89 @@
+package argon2id
+
+import (
+	"runtime/debug"
+	"runtime/metrics"
+	"sync"
+	"time"
+)
+
+// stressSampleInterval is how often StressHash samples memory usage.
+const stressSampleInterval = 2 * time.Millisecond
+
+// StressHash runs concurrency goroutines that each generate iterations
+// hashes with params, and reports the peak memory observed.
+//
+// It lets users check that their chosen params and concurrency fit their
+// instance size before production traffic does, and lets this package's CI
+// catch memory regressions. Compare the result against
+// EstimateMemory(params) * concurrency.
+//
+// The peak is the highest growth during the run of the memory the Go
+// runtime has mapped and not returned to the OS, which approximates the
+// process RSS: the runtime/metrics "/memory/classes/total:bytes" value less
+// "/memory/classes/heap/released:bytes". It is measured from a baseline
+// taken after debug.FreeOSMemory, so memory left over by earlier work in
+// the process is not counted. If params is nil, DefaultParams() will be used.
+// The first hashing error, if any, is returned.
+func StressHash(params *Params, concurrency, iterations int) (peakBytes uint64, err error) {
+	if params == nil {
+		params = DefaultParams()
+	}
+	if err := validateParams(params); err != nil {
+		return 0, err
+	}
+
+	samples := []metrics.Sample{
+		{Name: "/memory/classes/total:bytes"},
+		{Name: "/memory/classes/heap/released:bytes"},
+	}
+	retained := func() uint64 {
+		metrics.Read(samples)
+		return samples[0].Value.Uint64() - samples[1].Value.Uint64()
+	}
+	debug.FreeOSMemory()
+	baseline := retained()
+	peak := func() {
+		if r := retained(); r > baseline {
+			peakBytes = max(peakBytes, r-baseline)
+		}
+	}
+
+	done := make(chan struct{})
+	sampled := make(chan struct{})
+	go func() {
+		defer close(sampled)
+		ticker := time.NewTicker(stressSampleInterval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-done:
+				return
+			case <-ticker.C:
+				peak()
+			}
+		}
+	}()
+
+	var once sync.Once
+	var wg sync.WaitGroup
+	for i := 0; i < concurrency; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < iterations; j++ {
+				if _, hashErr := GenerateFromPassword([]byte("stress"), params); hashErr != nil {
+					once.Do(func() { err = hashErr })
+					return
+				}
+			}
+		}()
+	}
+	wg.Wait()
+
+	close(done)
+	<-sampled
+	peak()
+
+	return peakBytes, err
+}
@@ -0,0 +1,58 @@
+package argon2id
+
+import (
+	"flag"
+	"testing"
+)
+
+// Run with: go test -run TestStressHash . -stress (also runs the
+// MaxMemory cases of the boundary tests)
+var stress = flag.Bool("stress", false, "run memory stress tests")
+
+func TestEstimateMemory(t *testing.T) {
+	tests := []struct {
+		params *Params
+		want   uint64
+	}{
+		{nil, 64 * 1024 * 1024},
+		{&Params{Memory: 1024, Threads: 1}, 1024 * 1024},
+		{&Params{Memory: 1023, Threads: 1}, 1020 * 1024}, // rounded down to 4 blocks per lane
+		{&Params{Memory: 8, Threads: 4}, 32 * 1024},      // floor of 8 blocks per thread
+		{&Params{Memory: 1024, Threads: 0}, 0},
+	}
+
+	for _, tt := range tests {
+		if got := EstimateMemory(tt.params); got != tt.want {
+			t.Errorf("EstimateMemory(%+v) = %d, want %d", tt.params, got, tt.want)
+		}
+	}
+}
+
+func TestStressHash(t *testing.T) {
+	if !*stress {
+		t.Skip("stress tests disabled; run with -stress")
+	}
+
+	params := DefaultParams()
+	concurrency := 4
+
+	peak, err := StressHash(params, concurrency, 3)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	estimate := EstimateMemory(params) * uint64(concurrency)
+	t.Logf("peak %d MB, estimate %d MB", peak>>20, estimate>>20)
+
+	// The runtime needs some headroom over the raw Argon2 blocks, but a
+	// peak far above the estimate means memory is being retained or leaked
+	if peak > 3*estimate {
+		t.Errorf("peak memory %d bytes far exceeds estimate %d bytes", peak, estimate)
+	}
+}
+
+func TestStressHashInvalidParams(t *testing.T) {
+	if _, err := StressHash(&Params{}, 1, 1); err == nil {
+		t.Error("expected error for invalid params")
+	}
+}
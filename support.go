@@ -0,0 +1,37 @@
+package argon2id
+
+import "golang.org/x/crypto/argon2"
+
+// SupportedVersions returns the Argon2 versions (the "v=" field of a PHC
+// string) this build can verify.
+//
+// During a rolling deployment, nodes can advertise it so verification is
+// routed to a node that understands a given hash; see also CanVerify.
+func SupportedVersions() []int {
+	return []int{argon2.Version}
+}
+
+// SupportedVariants returns the Argon2 variants this build can verify.
+func SupportedVariants() []string {
+	return []string{"argon2id"}
+}
+
+// CanVerify reports whether CompareHashAndPassword on this build could
+// verify hash, without hashing anything.
+//
+// It returns false if the hash is malformed, uses a variant or version not
+// listed by SupportedVariants and SupportedVersions, exceeds the
+// verify-time limits (MaxTime, MaxMemory) or has a digest wrapped by a
+// DigestTransform, which only a Hasher configured with it can unwrap. It
+// always returns false in FIPS builds, where verification is refused. A
+// true result says nothing about whether any particular password matches.
+func CanVerify(hash []byte) bool {
+	if fipsRestricted {
+		return false
+	}
+	params, _, _, err := decodeHash(string(hash), nil)
+	if err != nil || hasPostHashMarker(params.Extra) {
+		return false
+	}
+	return params.Time <= MaxTime && params.Memory <= MaxMemory
+}
@@ -0,0 +1,52 @@
+//go:build !fips && !goexperiment.boringcrypto
+
+package argon2id
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestSupported(t *testing.T) {
+	if !slices.Contains(SupportedVersions(), 19) {
+		t.Errorf("expected version 19 to be supported, got %v", SupportedVersions())
+	}
+	if !slices.Equal(SupportedVariants(), []string{"argon2id"}) {
+		t.Errorf("expected only argon2id, got %v", SupportedVariants())
+	}
+}
+
+func TestCanVerify(t *testing.T) {
+	hash, err := GenerateFromPassword([]byte("password"), &Params{Time: 1, Memory: 1024, Threads: 1, KeyLen: 32})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !CanVerify(hash) {
+		t.Errorf("expected %s to be verifiable", hash)
+	}
+
+	// A wrapped digest needs the Hasher holding its transform
+	h := NewHasher(&Params{Time: 1, Memory: 1024, Threads: 1, KeyLen: 32, PostHash: xorTransform{key: 0x5c}})
+	wrapped, err := h.GenerateFromPassword([]byte("password"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if CanVerify(wrapped) {
+		t.Errorf("expected wrapped %s not to be verifiable", wrapped)
+	}
+
+	tests := []struct {
+		name string
+		hash string
+	}{
+		{"malformed", "not a hash"},
+		{"argon2i", "$argon2i$v=19$m=65536,t=1,p=2$mFe3kxhovyEByvwnUtr0ow$nU9AqnoPfzMOQhCHa9BDrQ+4bSfj69jgtvGu/2McCxU"},
+		{"version 16", "$argon2id$v=16$m=65536,t=1,p=2$mFe3kxhovyEByvwnUtr0ow$nU9AqnoPfzMOQhCHa9BDrQ+4bSfj69jgtvGu/2McCxU"},
+		{"too expensive", "$argon2id$v=19$m=4194304,t=1,p=2$mFe3kxhovyEByvwnUtr0ow$nU9AqnoPfzMOQhCHa9BDrQ+4bSfj69jgtvGu/2McCxU"},
+	}
+	for _, tt := range tests {
+		if CanVerify([]byte(tt.hash)) {
+			t.Errorf("%s: expected %q not to be verifiable", tt.name, tt.hash)
+		}
+	}
+}
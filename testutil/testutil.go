@@ -0,0 +1,107 @@
+// Package testutil loads Argon2ID hash fixtures for tests and tooling and
+// provides assertions for round-trip tests.
+//
+// Fixtures are stored one per line as the hash and the password separated
+// by a tab:
+//
+//	$argon2id$v=19$m=1024,t=1,p=1$kfMZSIqPHqfW/B1o7oC72w$XrJ41FOgmhgmoP9my+TtCsSBZiiqfsaT4++z1Vk9C4k	password
+//
+// Blank lines and lines starting with '#' are ignored by LoadFixtures.
+package testutil
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/sixcolors/argon2id"
+)
+
+// ErrMalformedLine is returned for a fixture line without a tab separator.
+var ErrMalformedLine = errors.New("testutil: fixture line is not hash<TAB>password")
+
+// ParseFixtureLine splits a "hash<TAB>password" fixture line.
+//
+// The line is split at the first tab, so the password may itself contain
+// tabs. A trailing "\r" is stripped. The hash is validated with
+// argon2id.IsArgon2idHash; argon2id.ErrInvalidHash is returned if it is
+// not a well-formed Argon2ID hash.
+func ParseFixtureLine(line string) (hash, password []byte, err error) {
+	line = strings.TrimSuffix(line, "\r")
+	h, p, found := strings.Cut(line, "\t")
+	if !found {
+		return nil, nil, ErrMalformedLine
+	}
+	if !argon2id.IsArgon2idHash([]byte(h)) {
+		return nil, nil, argon2id.ErrInvalidHash
+	}
+	return []byte(h), []byte(p), nil
+}
+
+// LoadFixtures reads fixture lines from r.
+//
+// Blank lines and lines starting with '#' are skipped. Errors from
+// ParseFixtureLine are wrapped with the offending line number.
+func LoadFixtures(r io.Reader) ([]argon2id.HashPasswordPair, error) {
+	var pairs []argon2id.HashPasswordPair
+	scanner := bufio.NewScanner(r)
+	for n := 1; scanner.Scan(); n++ {
+		line := scanner.Text()
+		if strings.TrimSpace(line) == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		hash, password, err := ParseFixtureLine(line)
+		if err != nil {
+			return nil, fmt.Errorf("testutil: line %d: %w", n, err)
+		}
+		pairs = append(pairs, argon2id.HashPasswordPair{Hash: hash, Password: password})
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	return pairs, nil
+}
+
+// AssertRoundTrip hashes password with params and checks the invariants every
+// hash must satisfy: it is recognized by argon2id.IsArgon2idHash,
+// argon2id.ExtractParams returns the cost parameters and key length it was
+// generated with, and argon2id.CompareHashAndPassword accepts password and
+// rejects a different one. Failures are reported on t. It returns the hash.
+// If params is nil, argon2id.DefaultParams() will be used.
+func AssertRoundTrip(t testing.TB, password []byte, params *argon2id.Params) []byte {
+	t.Helper()
+
+	if params == nil {
+		params = argon2id.DefaultParams()
+	}
+
+	hash, err := argon2id.GenerateFromPassword(password, params)
+	if err != nil {
+		t.Fatalf("GenerateFromPassword(%+v): %v", params, err)
+	}
+
+	if !argon2id.IsArgon2idHash(hash) {
+		t.Errorf("IsArgon2idHash(%s) = false", hash)
+	}
+
+	extracted, err := argon2id.ExtractParams(hash)
+	if err != nil {
+		t.Errorf("ExtractParams(%s): %v", hash, err)
+	} else if extracted.Time != params.Time || extracted.Memory != params.Memory ||
+		extracted.Threads != params.Threads || extracted.KeyLen != params.KeyLen {
+		t.Errorf("ExtractParams(%s) = %+v, expected %+v", hash, extracted, params)
+	}
+
+	if err := argon2id.CompareHashAndPassword(hash, password); err != nil {
+		t.Errorf("CompareHashAndPassword(%s) with the right password: %v", hash, err)
+	}
+	wrong := append([]byte("x"), password...)
+	if err := argon2id.CompareHashAndPassword(hash, wrong); err != argon2id.ErrMismatchedHashAndPassword {
+		t.Errorf("CompareHashAndPassword(%s) with a wrong password: expected %v, got %v", hash, argon2id.ErrMismatchedHashAndPassword, err)
+	}
+
+	return hash
+}
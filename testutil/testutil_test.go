@@ -0,0 +1,106 @@
+//go:build !fips && !goexperiment.boringcrypto
+
+package testutil
+
+import (
+	"errors"
+	"math/rand/v2"
+	"strings"
+	"testing"
+
+	"github.com/sixcolors/argon2id"
+)
+
+const (
+	passwordHash = "$argon2id$v=19$m=1024,t=1,p=1$kfMZSIqPHqfW/B1o7oC72w$XrJ41FOgmhgmoP9my+TtCsSBZiiqfsaT4++z1Vk9C4k"
+	tabbedHash   = "$argon2id$v=19$m=1024,t=1,p=1$rPLMLfxlUwZbsSV589BQqA$gcwfFcYO4m+NDDCDeL7W41Wf2KEklgRp0RUeTfw3RmY"
+)
+
+func TestParseFixtureLine(t *testing.T) {
+	hash, password, err := ParseFixtureLine(tabbedHash + "\tpa$$\tword\r")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(hash) != tabbedHash {
+		t.Errorf("expected hash %q, got %q", tabbedHash, hash)
+	}
+	if string(password) != "pa$$\tword" {
+		t.Errorf("expected password %q, got %q", "pa$$\tword", password)
+	}
+
+	if _, _, err := ParseFixtureLine(passwordHash); err != ErrMalformedLine {
+		t.Errorf("expected %v for a line without a tab, got %v", ErrMalformedLine, err)
+	}
+	if _, _, err := ParseFixtureLine("$2a$10$notargon2\tpassword"); err != argon2id.ErrInvalidHash {
+		t.Errorf("expected %v for a non-argon2id hash, got %v", argon2id.ErrInvalidHash, err)
+	}
+}
+
+func TestLoadFixtures(t *testing.T) {
+	input := "# known-good vectors\n" +
+		passwordHash + "\tpassword\n" +
+		"\n" +
+		tabbedHash + "\tpa$$\tword\n"
+
+	pairs, err := LoadFixtures(strings.NewReader(input))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(pairs) != 2 {
+		t.Fatalf("expected 2 fixtures, got %d", len(pairs))
+	}
+	for _, pair := range pairs {
+		if err := argon2id.CompareHashAndPassword(pair.Hash, pair.Password); err != nil {
+			t.Errorf("fixture %s: %v", pair.Hash, err)
+		}
+	}
+
+	_, err = LoadFixtures(strings.NewReader(passwordHash + "\tpassword\nnot a fixture\n"))
+	if !errors.Is(err, ErrMalformedLine) || !strings.Contains(err.Error(), "line 2") {
+		t.Errorf("expected line 2 to be reported as malformed, got %v", err)
+	}
+}
+
+// TestRoundTripProperty checks AssertRoundTrip's invariants for the boundary
+// params (except those too large to hash quickly) and for random valid
+// params, which exercise many-digit values the targeted tests miss. The
+// random cost is bounded (at most 16 passes over 2 MiB) to keep it fast;
+// the boundary params cover the limits.
+func TestRoundTripProperty(t *testing.T) {
+	var cases []*argon2id.Params
+	for _, params := range argon2id.AllValidBoundaryParams() {
+		if params.Memory <= argon2id.DefaultMemory {
+			cases = append(cases, params)
+		}
+	}
+
+	rng := rand.New(rand.NewPCG(1, 2))
+	for range 20 {
+		cases = append(cases, &argon2id.Params{
+			Time:    argon2id.MinTime + rng.Uint32N(16),
+			Memory:  argon2id.MinMemory + rng.Uint32N(2048),
+			Threads: uint8(argon2id.MinThreads + rng.IntN(255)), // #nosec G115 - at most 255
+			KeyLen:  argon2id.MinKeyLen + rng.Uint32N(argon2id.MaxKeyLen-argon2id.MinKeyLen+1),
+		})
+	}
+
+	for _, params := range cases {
+		password := make([]byte, rng.IntN(64))
+		for i := range password {
+			password[i] = byte(rng.Uint32()) // #nosec G115 - truncation intended
+		}
+		AssertRoundTrip(t, password, params)
+	}
+}
+
+func TestAssertRoundTripDefaults(t *testing.T) {
+	hash := AssertRoundTrip(t, []byte("password"), nil)
+
+	params, err := argon2id.ExtractParams(hash)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !params.Equal(argon2id.DefaultParams()) {
+		t.Errorf("expected default params, got %+v", params)
+	}
+}
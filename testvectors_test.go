@@ -0,0 +1,174 @@
+//go:build !fips && !goexperiment.boringcrypto
+
+package argon2id
+
+import (
+	"bytes"
+	"encoding/hex"
+	"testing"
+
+	"golang.org/x/crypto/argon2"
+)
+
+// Test vectors from the Argon2 reference implementation
+// (https://github.com/P-H-C/phc-winner-argon2, src/test.c), version 0x13,
+// password "password", salt "somesalt", 32-byte tag.
+//
+// The RFC 9106 section 5 vectors additionally use a secret key and
+// associated data, which golang.org/x/crypto/argon2 does not expose, so they
+// cannot be reproduced through this package.
+type testVector struct {
+	name    string
+	tag     string // expected tag in hex
+	time    uint32
+	memory  uint32 // in KB
+	threads uint8
+}
+
+// argon2idVectors are Argon2id (type 2) vectors, verified through this package.
+var argon2idVectors = []testVector{
+	{"argon2id m=2^16,t=2,p=1", "09316115d5cf24ed5a15a31a3ba326e5cf32edc24702987c02b6566f61913cf7", 2, 1 << 16, 1},
+	{"argon2id m=2^18,t=2,p=1", "78fe1ec91fb3aa5657d72e710854e4c3d9b9198c742f9616c2f085bed95b2e8c", 2, 1 << 18, 1},
+	{"argon2id m=2^8,t=2,p=1", "9dfeb910e80bad0311fee20f9c0e2b12c17987b4cac90c2ef54d5b3021c68bfe", 2, 1 << 8, 1},
+	{"argon2id m=2^8,t=2,p=2", "6d093c501fd5999645e0ea3bf620d7b8be7fd2db59c20d9fff9539da2bf57037", 2, 1 << 8, 2},
+	{"argon2id m=2^16,t=1,p=1", "f6a5adc1ba723dddef9b5ac1d464e180fcd9dffc9d1cbf76cca2fed795d9ca98", 1, 1 << 16, 1},
+	{"argon2id m=2^16,t=4,p=1", "9025d48e68ef7395cca9079da4c4ec3affb3c8911fe4f86d1a2520856f63172c", 4, 1 << 16, 1},
+}
+
+// argon2iVectors are Argon2i (type 1) vectors. This package only produces
+// Argon2id, so they are checked directly against the upstream argon2.Key to
+// document that the dependency as a whole matches the reference.
+var argon2iVectors = []testVector{
+	{"argon2i m=2^16,t=2,p=1", "c1628832147d9720c5bd1cfd61367078729f6dfb6f8fea9ff98158e0d7816ed0", 2, 1 << 16, 1},
+	{"argon2i m=2^8,t=2,p=1", "89e9029f4637b295beb027056a7336c414fadd43f6b208645281cb214a56452f", 2, 1 << 8, 1},
+}
+
+func TestArgon2idVectors(t *testing.T) {
+	for _, v := range argon2idVectors {
+		t.Run(v.name, func(t *testing.T) {
+			params := &Params{Time: v.time, Memory: v.memory, Threads: v.threads, KeyLen: 32}
+
+			tag, err := DeriveKey([]byte("password"), []byte("somesalt"), params)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := hex.EncodeToString(tag); got != v.tag {
+				t.Errorf("expected tag %s, got %s", v.tag, got)
+			}
+		})
+	}
+}
+
+func TestArgon2iVectors(t *testing.T) {
+	for _, v := range argon2iVectors {
+		t.Run(v.name, func(t *testing.T) {
+			tag := argon2.Key([]byte("password"), []byte("somesalt"), v.time, v.memory, v.threads, 32)
+			if got := hex.EncodeToString(tag); got != v.tag {
+				t.Errorf("expected tag %s, got %s", v.tag, got)
+			}
+		})
+	}
+}
+
+// pinnedHashes record the exact encoded output of GenerateFromPasswordWithSalt
+// for fixed inputs. Unlike the reference vectors above, they cover the whole
+// path a stored hash takes (parameter handling, Argon2 and encoding), so a
+// golang.org/x/crypto bump that changes argon2.IDKey output in any way fails
+// here instead of silently invalidating every stored hash.
+//
+// A failure in TestPinnedHashes means the dependency or encoding is no
+// longer compatible with existing hashes; do not update the expected values
+// to make it pass.
+var pinnedHashes = []struct {
+	params *Params
+	hash   string
+}{
+	{DefaultParams(), "$argon2id$v=19$m=65536,t=3,p=2$MDEyMzQ1Njc4OWFiY2RlZg$UqcjN8FEhR9C3aVPAePbwhjlawq6mqKGGOFCbIG7i8U"},
+	{&Params{Time: 2, Memory: 1 << 12, Threads: 2, KeyLen: 32}, "$argon2id$v=19$m=4096,t=2,p=2$MDEyMzQ1Njc4OWFiY2RlZg$l+sShgBa9b6bDdtlyeNLztjyuIOUzpYFSNhBxSR9kTs"},
+}
+
+func TestPinnedHashes(t *testing.T) {
+	password := []byte("correct horse battery staple")
+	salt := []byte("0123456789abcdef")
+
+	for _, v := range pinnedHashes {
+		hash, err := GenerateFromPasswordWithSalt(password, salt, v.params)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(hash) != v.hash {
+			t.Errorf("incompatible hash output for %+v:\nexpected %s\ngot      %s", *v.params, v.hash, hash)
+		}
+		if err := CompareHashAndPassword([]byte(v.hash), password); err != nil {
+			t.Errorf("pinned hash %s no longer verifies: %v", v.hash, err)
+		}
+	}
+}
+
+func TestDeriveKeyValidation(t *testing.T) {
+	if _, err := DeriveKey([]byte("password"), []byte("short"), nil); err == nil {
+		t.Error("expected error for salt shorter than MinSaltLen")
+	}
+	if _, err := DeriveKey([]byte("password"), []byte("somesalt"), &Params{}); err == nil {
+		t.Error("expected error for invalid params")
+	}
+}
+
+func TestDeriveSubkeys(t *testing.T) {
+	params := &Params{Time: 1, Memory: 1024, Threads: 1, KeyLen: 32}
+	lengths := []uint32{16, 32, 32, 64}
+
+	subkeys, err := DeriveSubkeys([]byte("password"), []byte("somesalt"), params, lengths)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(subkeys) != len(lengths) {
+		t.Fatalf("expected %d subkeys, got %d", len(lengths), len(subkeys))
+	}
+	for i, key := range subkeys {
+		if len(key) != int(lengths[i]) {
+			t.Errorf("subkey %d: expected %d bytes, got %d", i, lengths[i], len(key))
+		}
+		for j := range i {
+			if bytes.Equal(key[:16], subkeys[j][:16]) {
+				t.Errorf("subkeys %d and %d are not distinct", j, i)
+			}
+		}
+	}
+
+	again, err := DeriveSubkeys([]byte("password"), []byte("somesalt"), params, lengths)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := range subkeys {
+		if !bytes.Equal(subkeys[i], again[i]) {
+			t.Errorf("subkey %d is not deterministic", i)
+		}
+	}
+
+	// The subkeys are not the Argon2 output itself
+	master, err := DeriveKey([]byte("password"), []byte("somesalt"), params)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if bytes.Equal(subkeys[1], master) {
+		t.Error("expected subkeys to differ from the Argon2 output")
+	}
+
+	other, err := DeriveSubkeys([]byte("other"), []byte("somesalt"), params, lengths)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if bytes.Equal(other[0], subkeys[0]) {
+		t.Error("expected a different password to give different subkeys")
+	}
+
+	for _, bad := range [][]uint32{{0}, {255*32 + 1}} {
+		if _, err := DeriveSubkeys([]byte("password"), []byte("somesalt"), params, bad); err == nil {
+			t.Errorf("expected error for lengths %v", bad)
+		}
+	}
+	if _, err := DeriveSubkeys([]byte("password"), []byte("short"), params, lengths); err == nil {
+		t.Error("expected error for a short salt")
+	}
+}
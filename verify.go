@@ -0,0 +1,78 @@
+package argon2id
+
+import (
+	"time"
+
+	"golang.org/x/crypto/argon2"
+)
+
+// VerifyResult is the outcome of VerifyDetailed.
+//
+// Params are the parameters the Argon2 computation actually ran with: those
+// decoded from the hash, or for compact encodings the Hasher's. Together
+// with Elapsed they let a slow or suspiciously fast verification be traced
+// to the cost that was really paid rather than the cost that was configured.
+type VerifyResult struct {
+	Params      *Params       // Params the computation ran with
+	Variant     string        // Argon2 variant, always "argon2id"
+	Version     int           // Argon2 version, e.g. 19
+	Elapsed     time.Duration // Duration of the Argon2 computation (see CompareHashAndPasswordTimed)
+	Matched     bool          // Whether the password matched
+	NeedsRehash bool          // Whether Params are weaker than desired (see NeedsRehash)
+}
+
+// VerifyDetailed verifies password against hashedPassword and reports
+// everything a login handler needs in one call: whether it matched, the
+// stored params and whether they should be upgraded to desired.
+//
+// It parses the hash once and computes one hash, unlike calling
+// CompareHashAndPassword, ExtractParams and NeedsRehash separately. A
+// mismatch is reported as Matched = false with a nil error; errors are
+// returned for hashes that cannot be verified at all (e.g. malformed, or
+// ErrHashTooExpensive as with CompareHashAndPassword). NeedsRehash is
+// reported whether or not the password matched, but a hash can only be
+// upgraded after a match. If desired is nil, DefaultParams() will be used.
+func VerifyDetailed(hashedPassword, password []byte, desired *Params) (VerifyResult, error) {
+	if desired == nil {
+		desired = DefaultParams()
+	}
+	return verifyDetailed(hashedPassword, password, desired, verifyOptions{enforceLimits: true})
+}
+
+// VerifyDetailed is the Hasher counterpart of the package-level
+// VerifyDetailed: it verifies like Hasher.CompareHashAndPassword, including
+// MaxCandidateLen and ShadowVerify, and reports NeedsRehash against the
+// Hasher's params. For a compact hash the reported Params are the Hasher's,
+// which makes a misconfigured Hasher visible.
+func (h *Hasher) VerifyDetailed(hashedPassword, password []byte) (VerifyResult, error) {
+	desired := h.Params
+	if desired == nil {
+		desired = DefaultParams()
+	}
+
+	_, params, elapsed, err := h.verify(hashedPassword, password)
+	return detailedResult(params, elapsed, err, desired)
+}
+
+// verifyDetailed implements VerifyDetailed with the given verify options.
+func verifyDetailed(hashedPassword, password []byte, desired *Params, opts verifyOptions) (VerifyResult, error) {
+	params, elapsed, err := verifyHash(hashedPassword, password, opts)
+	return detailedResult(params, elapsed, err, desired)
+}
+
+// detailedResult builds the VerifyResult of a verification that returned
+// params, elapsed and err (see verifyHash).
+func detailedResult(params *Params, elapsed time.Duration, err error, desired *Params) (VerifyResult, error) {
+	if err != nil && err != ErrMismatchedHashAndPassword {
+		return VerifyResult{}, err
+	}
+
+	return VerifyResult{
+		Params:      params,
+		Variant:     "argon2id",
+		Version:     argon2.Version,
+		Elapsed:     elapsed,
+		Matched:     err == nil,
+		NeedsRehash: weakerThan(params, desired),
+	}, nil
+}
@@ -0,0 +1,105 @@
+//go:build !fips && !goexperiment.boringcrypto
+
+package argon2id
+
+import (
+	"testing"
+	"time"
+)
+
+func TestVerifyDetailed(t *testing.T) {
+	weak := &Params{Time: 1, Memory: 1024, Threads: 1, KeyLen: 32}
+	strong := &Params{Time: 2, Memory: 1024, Threads: 1, KeyLen: 32}
+
+	hash, err := GenerateFromPassword([]byte("password"), weak)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name        string
+		password    string
+		desired     *Params
+		matched     bool
+		needsRehash bool
+	}{
+		{"matched, current", "password", weak, true, false},
+		{"matched, upgrade needed", "password", strong, true, true},
+		{"mismatched, current", "wrong", weak, false, false},
+		{"mismatched, upgrade needed", "wrong", strong, false, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result, err := VerifyDetailed(hash, []byte(tt.password), tt.desired)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if result.Matched != tt.matched || result.NeedsRehash != tt.needsRehash {
+				t.Errorf("expected Matched=%v NeedsRehash=%v, got %+v", tt.matched, tt.needsRehash, result)
+			}
+			if result.Params == nil || result.Params.Time != 1 || result.Params.Memory != 1024 || result.Params.KeyLen != 32 {
+				t.Errorf("unexpected params %+v", result.Params)
+			}
+			if result.Variant != "argon2id" || result.Version != 19 {
+				t.Errorf("unexpected variant/version %s/%d", result.Variant, result.Version)
+			}
+		})
+	}
+
+	if _, err := VerifyDetailed([]byte("not a hash"), []byte("password"), nil); err == nil {
+		t.Error("expected error for a malformed hash")
+	}
+}
+
+func TestHasherVerifyDetailed(t *testing.T) {
+	// Each clock reading advances a fake clock so Elapsed is deterministic
+	now := time.Unix(0, 0)
+	SetTimeSource(func() time.Time {
+		now = now.Add(10 * time.Millisecond)
+		return now
+	})
+	t.Cleanup(func() { SetTimeSource(nil) })
+
+	configured := &Params{Time: 2, Memory: 2048, Threads: 1, KeyLen: 32, Encoding: EncodingRaw}
+	hash, err := GenerateFromPassword([]byte("password"), configured)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var shadowed int
+	hasher := NewHasher(configured)
+	hasher.ShadowVerify = func([]byte) { shadowed++ }
+	result, err := hasher.VerifyDetailed(hash, []byte("password"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !result.Matched || result.NeedsRehash {
+		t.Errorf("expected a current match, got %+v", result)
+	}
+	if !result.Params.Equal(configured) {
+		t.Errorf("expected the compact hash to run with the Hasher's params, got %+v", result.Params)
+	}
+	if result.Elapsed != 10*time.Millisecond {
+		t.Errorf("expected Elapsed of 10ms, got %s", result.Elapsed)
+	}
+
+	// A misconfigured Hasher is visible in the reported params
+	weaker := configured.Clone()
+	weaker.Time = 1
+	result, err = NewHasher(weaker).VerifyDetailed(hash, []byte("password"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.Matched || result.Params.Time != 1 {
+		t.Errorf("expected a mismatch at t=1, got %+v", result)
+	}
+
+	hasher.MaxCandidateLen = 4
+	if _, err := hasher.VerifyDetailed(hash, []byte("password")); err != ErrPasswordTooLong {
+		t.Errorf("expected ErrPasswordTooLong, got %v", err)
+	}
+	if shadowed != 2 {
+		t.Errorf("expected ShadowVerify to run for every attempt, got %d", shadowed)
+	}
+}
@@ -0,0 +1,160 @@
+package argon2id
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/binary"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// VerifyCache remembers recent successful verifications so that repeating
+// the same one within a short TTL skips the key derivation.
+//
+// It is meant for hot, read-only verification paths such as API keys sent
+// on every request, where the same (hash, secret) pair is checked many
+// times a second. It is opt-in; nothing in the package uses it by default.
+// Login forms should not use it: there the cost of Argon2 is the point.
+//
+// # Threat model
+//
+// Entries are keyed by HMAC-SHA256 over the hash and the password under a
+// random key generated by NewVerifyCache and held only in memory. The cache
+// stores no passwords and no unkeyed digests of them, so leaked entries
+// alone do not allow offline guessing. An attacker who obtains both the
+// entries and the key (a full process memory dump) can, however, test
+// guesses against the currently cached entries at HMAC speed rather than
+// Argon2 speed. Such an attacker can usually also read plaintext passwords
+// in flight; a short TTL and a small size limit what is exposed.
+//
+// Only successful verifications are cached, so a wrong password always pays
+// the full Argon2 cost and the cache cannot be used to speed up guessing.
+// Lookups are by unpredictable HMAC tag, so timing differences between a
+// hit and a miss reveal nothing an attacker does not already know (whether
+// they presented a valid credential in the last TTL).
+//
+// Because entries include the hash, changing a user's password or rotating
+// an API key invalidates their entries implicitly: the new hash produces
+// different tags. A cached success for the old hash can only be reached by
+// presenting the old hash, which the application no longer has. Purge drops
+// everything, e.g. on suspected compromise. Revocations that do not change
+// the stored hash, such as disabling an account, are invisible to the cache:
+// check them before verifying, and keep the TTL short (seconds to minutes).
+//
+// The cache holds at most maxEntries entries; when full, expired entries are
+// evicted first and then arbitrary ones. A VerifyCache is safe for
+// concurrent use.
+type VerifyCache struct {
+	entries    map[[sha256.Size]byte]time.Time
+	key        []byte
+	mu         sync.Mutex
+	ttl        time.Duration
+	maxEntries int
+}
+
+// NewVerifyCache returns an empty cache whose entries expire after ttl and
+// which holds at most maxEntries entries.
+func NewVerifyCache(ttl time.Duration, maxEntries int) (*VerifyCache, error) {
+	if ttl <= 0 {
+		return nil, fmt.Errorf("argon2id: cache TTL (%s) must be positive", ttl)
+	}
+	if maxEntries < 1 {
+		return nil, fmt.Errorf("argon2id: cache size (%d) must be at least 1", maxEntries)
+	}
+
+	key := make([]byte, sha256.Size)
+	if _, err := rand.Read(key); err != nil {
+		return nil, err
+	}
+
+	return &VerifyCache{
+		entries:    make(map[[sha256.Size]byte]time.Time),
+		key:        key,
+		ttl:        ttl,
+		maxEntries: maxEntries,
+	}, nil
+}
+
+// CompareHashAndPassword is like the package-level CompareHashAndPassword
+// but returns nil without hashing if the same hash and password verified
+// successfully within the cache's TTL.
+func (c *VerifyCache) CompareHashAndPassword(hashedPassword, password []byte) error {
+	tag := c.tag(hashedPassword, password)
+	if c.hit(tag) {
+		return nil
+	}
+
+	if err := CompareHashAndPassword(hashedPassword, password); err != nil {
+		return err
+	}
+
+	c.store(tag)
+	return nil
+}
+
+// Purge removes all entries.
+func (c *VerifyCache) Purge() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	clear(c.entries)
+}
+
+// Len returns the number of entries, including expired ones not yet evicted.
+func (c *VerifyCache) Len() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return len(c.entries)
+}
+
+// tag computes the cache key for a hash and password. The hash is length
+// prefixed so that no two (hash, password) pairs share an input.
+func (c *VerifyCache) tag(hashedPassword, password []byte) [sha256.Size]byte {
+	mac := hmac.New(sha256.New, c.key)
+	_ = binary.Write(mac, binary.BigEndian, uint64(len(hashedPassword))) // #nosec G115 - len() is non-negative
+	mac.Write(hashedPassword)
+	mac.Write(password)
+
+	var tag [sha256.Size]byte
+	mac.Sum(tag[:0])
+	return tag
+}
+
+// hit reports whether tag is cached and unexpired, evicting it if expired.
+func (c *VerifyCache) hit(tag [sha256.Size]byte) bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	expires, ok := c.entries[tag]
+	if !ok {
+		return false
+	}
+	if !nowFunc().Before(expires) {
+		delete(c.entries, tag)
+		return false
+	}
+	return true
+}
+
+// store caches tag, making room if the cache is full.
+func (c *VerifyCache) store(tag [sha256.Size]byte) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	now := nowFunc()
+	if len(c.entries) >= c.maxEntries {
+		for t, expires := range c.entries {
+			if !now.Before(expires) {
+				delete(c.entries, t)
+			}
+		}
+	}
+	for t := range c.entries {
+		if len(c.entries) < c.maxEntries {
+			break
+		}
+		delete(c.entries, t)
+	}
+	c.entries[tag] = now.Add(c.ttl)
+}
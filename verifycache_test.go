@@ -0,0 +1,86 @@
+//go:build !fips && !goexperiment.boringcrypto
+
+package argon2id
+
+import (
+	"testing"
+	"time"
+)
+
+func TestVerifyCache(t *testing.T) {
+	now := time.Unix(0, 0)
+	SetTimeSource(func() time.Time { return now })
+	t.Cleanup(func() { SetTimeSource(nil) })
+
+	cache, err := NewVerifyCache(time.Minute, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	hash, err := GenerateFromPassword([]byte("api-key"), &Params{Time: 1, Memory: 1024, Threads: 1, KeyLen: 32})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := cache.CompareHashAndPassword(hash, []byte("api-key")); err != nil {
+		t.Fatal(err)
+	}
+	if cache.Len() != 1 {
+		t.Fatalf("expected a cached success, got %d entries", cache.Len())
+	}
+
+	// Failures are never cached
+	if err := cache.CompareHashAndPassword(hash, []byte("wrong")); err != ErrMismatchedHashAndPassword {
+		t.Errorf("expected %v, got %v", ErrMismatchedHashAndPassword, err)
+	}
+	if cache.Len() != 1 {
+		t.Errorf("expected failures not to be cached, got %d entries", cache.Len())
+	}
+
+	tag := cache.tag(hash, []byte("api-key"))
+	if !cache.hit(tag) {
+		t.Error("expected a hit within the TTL")
+	}
+
+	now = now.Add(time.Minute)
+	if cache.hit(tag) {
+		t.Error("expected the entry to expire after the TTL")
+	}
+	if cache.Len() != 0 {
+		t.Errorf("expected the expired entry to be evicted, got %d entries", cache.Len())
+	}
+}
+
+func TestVerifyCacheBounded(t *testing.T) {
+	cache, err := NewVerifyCache(time.Minute, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, password := range []string{"a", "b", "c", "d"} {
+		hash, err := GenerateFromPassword([]byte(password), &Params{Time: 1, Memory: 1024, Threads: 1, KeyLen: 32})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := cache.CompareHashAndPassword(hash, []byte(password)); err != nil {
+			t.Fatal(err)
+		}
+		if cache.Len() > 2 {
+			t.Fatalf("expected at most 2 entries, got %d", cache.Len())
+		}
+	}
+
+	cache.Purge()
+	if cache.Len() != 0 {
+		t.Errorf("expected Purge to empty the cache, got %d entries", cache.Len())
+	}
+}
+
+func TestNewVerifyCacheErrors(t *testing.T) {
+	if _, err := NewVerifyCache(0, 10); err == nil {
+		t.Error("expected error for a zero TTL")
+	}
+	if _, err := NewVerifyCache(time.Minute, 0); err == nil {
+		t.Error("expected error for a zero size")
+	}
+}